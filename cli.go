@@ -1,9 +1,13 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 	"sysdoc/internal/persistence"
+	"text/tabwriter"
+	"time"
 
 	"github.com/carlmjohnson/versioninfo"
 	"github.com/spf13/cobra"
@@ -13,8 +17,11 @@ type App struct {
 	flags struct {
 		configfile string
 		base       string
-		glob       string
+		glob       []string
 		focus      []string
+		owner      string
+		view       string
+		depth      int
 		git        struct {
 			url     string
 			user    string
@@ -26,12 +33,54 @@ type App struct {
 			renderer      string
 			out           string
 			noPostprocess bool
+			watch         bool
+			interval      string
 		}
 		serve struct {
 			renderer     string
 			listener     string
 			cacheTimeout string
 		}
+		stats struct {
+			format     string
+			interfaces bool
+		}
+		query struct {
+			records bool
+		}
+		owners struct {
+			format    string
+			team      string
+			crossTeam bool
+		}
+		mv struct {
+			format string
+			dryRun bool
+		}
+		fmt struct {
+			check bool
+		}
+		embed struct {
+			check bool
+		}
+		report struct {
+			format string
+		}
+		export struct {
+			format string
+			out    string
+		}
+		new struct {
+			name        string
+			description string
+			elementType string
+			owner       string
+			protocol    string
+			version     string
+			dependsOn   string
+			kind        string
+			criticality string
+		}
 	}
 
 	// Postprocessors
@@ -54,8 +103,11 @@ func NewApp() *App {
 	}
 	rootCmd.PersistentFlags().StringVar(&a.flags.configfile, "config", "sysdoc.yaml", "configuration file path relative to the base")
 	rootCmd.PersistentFlags().StringVar(&a.flags.base, "base", ".", "base directory of the sysdoc definitions")
-	rootCmd.PersistentFlags().StringVar(&a.flags.glob, "glob", "README.md", "glob to find sysdoc definitions")
+	rootCmd.PersistentFlags().StringSliceVar(&a.flags.glob, "glob", []string{"README.md"}, "globs to find sysdoc definitions, if several files in a directory match the first glob takes precedence")
 	rootCmd.PersistentFlags().StringSliceVar(&a.flags.focus, "focus", []string{}, "elements to be focussed")
+	rootCmd.PersistentFlags().StringVar(&a.flags.owner, "owner", "", "focus on all elements owned by the given team")
+	rootCmd.PersistentFlags().StringVar(&a.flags.view, "view", "", "C4 view to be shown (context, container or component), elements are collapsed according to their type")
+	rootCmd.PersistentFlags().IntVar(&a.flags.depth, "depth", 0, "collapse all elements nested deeper than the given depth (0 shows all elements)")
 	rootCmd.PersistentFlags().StringVar(&a.flags.git.url, "git.url", "", "url of git repo")
 	rootCmd.PersistentFlags().StringVar(&a.flags.git.user, "git.user", os.Getenv("GIT_USER"), "git user name (can be set via environment variable 'GIT_USER')")
 	rootCmd.PersistentFlags().StringVar(&a.flags.git.branch, "git.branch", "refs/heads/master", "git branch to be used")
@@ -72,8 +124,46 @@ func NewApp() *App {
 	renderCmd.PersistentFlags().StringVar(&a.flags.render.renderer, "renderer", "default", "name of the renederer (set of templates in the configuration file)")
 	renderCmd.PersistentFlags().StringVar(&a.flags.render.out, "out", "", "name of the file to be written (leave empty for STDOUT)")
 	renderCmd.PersistentFlags().BoolVar(&a.flags.render.noPostprocess, "no-postprocess", false, "do not run post processor")
+	renderCmd.PersistentFlags().BoolVar(&a.flags.render.watch, "watch", false, "render again whenever a file in the base changes, requires '--out'")
+	renderCmd.PersistentFlags().StringVar(&a.flags.render.interval, "interval", "1s", "interval in which the base is checked for changes when watching")
 	rootCmd.AddCommand(renderCmd)
 
+	// generate
+	generateCmd := &cobra.Command{
+		Use:   "generate",
+		Short: "renders all outputs declared in the configuration file",
+		Long: `With the subcommand 'generate', all outputs listed under 'outputs' in the configuration file
+are rendered in one run. The system is loaded once and the outputs are rendered in parallel.
+Every output declares the renderer to use, the path to write to and optionally a focus, a
+query, an owner, a C4 view and a depth to narrow down the elements, for example:
+
+  outputs:
+    - renderer: default
+      path: diagrams/backend.svg
+      query: BE.**
+      view: container
+      depth: 2
+    - renderer: default
+      path: diagrams/system.d2
+      format: source
+
+The format 'rendered' runs the postprocessor of the renderer, the format 'source' writes the
+output of the templates only. The global filter flags are ignored.
+
+Outputs with 'per_element' set generate one diagram focussed on each element, or on each
+element matching the query. The diagrams are named after 'file' and written to a directory
+tree below 'path' mirroring the element positions, or next to the definitions if 'path' is
+omitted, for example:
+
+  outputs:
+    - renderer: default
+      per_element: true
+      file: context.svg`,
+		Args: cobra.NoArgs,
+		Run:  a.generateCmd,
+	}
+	rootCmd.AddCommand(generateCmd)
+
 	// serve
 	serveCmd := &cobra.Command{
 		Use:   "serve",
@@ -84,9 +174,15 @@ a simple API to change the focus as well as the renderer on the fly:
 
 - To change the focus provide a list of elements, where every element is separated with a '+'.
 - To switch the renderer provide the 'renderer' query parameter.
+- To focus on all elements of a team provide the 'owner' query parameter.
+- To show a C4 view (context, container or component) provide the 'view' query parameter.
 
 For example, focus on the elements 'A.AB' and 'C' and render the output with a renderer
-called 'custom' using the following URL: http://localhost:8080/A.AB+C?renderer=custom`,
+called 'custom' using the following URL: http://localhost:8080/A.AB+C?renderer=custom
+
+Elements can be queried via http://localhost:8080/api/query?q=<expression>, see 'sysdoc query -h'
+for the syntax of the expression. Add the query parameter 'records=true' to get full records
+instead of IDs.`,
 		Run: a.serveCmd,
 	}
 	serveCmd.PersistentFlags().StringVar(&a.flags.serve.listener, "listener", "127.0.0.1:8080", "listener to be used by the http server")
@@ -94,6 +190,288 @@ called 'custom' using the following URL: http://localhost:8080/A.AB+C?renderer=c
 	serveCmd.PersistentFlags().StringVar(&a.flags.serve.cacheTimeout, "cache-timeout", "10m", "timeout of the internal cache")
 	rootCmd.AddCommand(serveCmd)
 
+	// stats
+	statsCmd := &cobra.Command{
+		Use:   "stats",
+		Short: "computes graph metrics of all elements and interfaces",
+		Long: `With the subcommand 'stats', metrics of the resolved dependency graph are computed:
+
+- Fan-in/fan-out: number of elements depending on an element or its interfaces and
+  number of elements an element depends on, directly and transitively.
+- Depth: number of hops to the most distant transitive dependency.
+- Criticality: share of all other elements which transitively depend on an element
+  or an interface.
+- SPOF: elements which split the dependency graph in two when removed.
+- Orphaned elements and unused interfaces.
+
+The metrics are also available in renderer templates via '.Metrics'.`,
+		Run: a.statsCmd,
+	}
+	statsCmd.PersistentFlags().StringVar(&a.flags.stats.format, "format", "table", "output format (table, csv or json)")
+	statsCmd.PersistentFlags().BoolVar(&a.flags.stats.interfaces, "interfaces", false, "report interface instead of element metrics (table and csv only)")
+	rootCmd.AddCommand(statsCmd)
+
+	// query
+	queryCmd := &cobra.Command{
+		Use:   "query [expression]",
+		Short: "lists all elements matching a query expression",
+		Long: `With the subcommand 'query', elements can be selected using a small query language:
+
+  BE.SVC          element with the given ID
+  BE.*, BE.**     glob on IDs, '*' matches a single segment, '**' any number of segments
+  tag(external)   elements which have the tag 'external' set
+  tag(type=user)  elements which have the tag 'type' set to 'user'
+  dependsOn(q)    elements which elements of q depend on
+  dependents(q)   elements which depend on elements of q
+  children(q)     direct children of elements of q
+  descendants(q)  all children of elements of q, recursively
+  parent(q)       direct parents of elements of q
+  ancestors(q)    all parents of elements of q, recursively
+  q | q           union
+  q & q           intersection
+  q - q           difference
+  (q)             grouping
+
+The difference needs spaces around '-', as '-' is allowed within IDs: 'BE.** - FE' removes
+FE from the result while 'BE.**-FE' is a single glob.
+
+For example, list all elements tagged 'external' which are depended on by something
+under 'BE' using the expression: 'tag(external) & dependsOn(BE.**)'`,
+		Args: cobra.ExactArgs(1),
+		Run:  a.queryCmd,
+	}
+	queryCmd.PersistentFlags().BoolVar(&a.flags.query.records, "records", false, "print full records as json instead of IDs")
+	rootCmd.AddCommand(queryCmd)
+
+	// impact
+	impactCmd := &cobra.Command{
+		Use:   "impact [element IDs]",
+		Short: "lists all elements affected by a failure of the given elements",
+		Long: `With the subcommand 'impact', all elements affected by a failure of the given elements
+are listed. Elements which have a dependency with criticality 'hard' on a failing element
+experience an outage, elements with a 'soft' dependency on a failing element or with any
+dependency on a degraded element experience a degradation.`,
+		Args: cobra.MinimumNArgs(1),
+		Run:  a.impactCmd,
+	}
+	rootCmd.AddCommand(impactCmd)
+
+	// lint
+	lintCmd := &cobra.Command{
+		Use:   "lint",
+		Short: "checks the system documentation for questionable definitions",
+		Long: `With the subcommand 'lint', the system documentation is loaded and checked. Errors which
+prevent the documentation from being loaded are reported as well as warnings about valid
+but questionable definitions such as:
+
+- Dependencies on planned, deprecated or retired interfaces.
+- Dependencies pinning a version or protocol not provided by the interface.
+
+Tags and custom fields are checked against the schema defined in the configuration file.
+
+The command exits with a non-zero exit code if any warnings are reported.`,
+		Run: a.lintCmd,
+	}
+	rootCmd.AddCommand(lintCmd)
+
+	// owners
+	ownersCmd := &cobra.Command{
+		Use:   "owners",
+		Short: "lists the owners of all elements and dependencies between teams",
+		Long: `With the subcommand 'owners', all teams are listed along with the elements they own. Elements
+inherit every owner field they do not declare, i.e. team, contact and oncall, from their parent
+element.
+
+Use '--cross-team' to list all dependencies between elements of different teams instead, and
+'--team' to only report a single team, for example to answer the question which interfaces of
+a team are used by other teams.`,
+		Run: a.ownersCmd,
+	}
+	ownersCmd.PersistentFlags().StringVar(&a.flags.owners.format, "format", "table", "output format (table or json)")
+	ownersCmd.PersistentFlags().StringVar(&a.flags.owners.team, "team", "", "only report the given team")
+	ownersCmd.PersistentFlags().BoolVar(&a.flags.owners.crossTeam, "cross-team", false, "list dependencies between teams instead of teams (table only)")
+	rootCmd.AddCommand(ownersCmd)
+
+	// report
+	reportCmd := &cobra.Command{
+		Use:   "report",
+		Short: "prints an interface catalogue and a dependency matrix",
+		Long: `With the subcommand 'report', a document is printed which consists of a catalogue of all
+interfaces with their element, team, description, protocol, version, lifecycle status, tags and
+consumers, followed by a matrix of the dependencies between the elements. The global filter
+flags narrow down the reported elements.
+
+The report is printed as Markdown, for example to be committed to a wiki, or as a standalone
+HTML document.`,
+		Args: cobra.NoArgs,
+		Run:  a.reportCmd,
+	}
+	reportCmd.PersistentFlags().StringVar(&a.flags.report.format, "format", "markdown", "output format (markdown, html or json)")
+	rootCmd.AddCommand(reportCmd)
+
+	// export
+	exportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "exports elements, interfaces and dependencies as tables",
+		Long: `With the subcommand 'export', the elements, interfaces and dependencies are exported as three
+tables with their full IDs, the IDs of their parents, the resolved targets of dependencies and
+one column per tag. The global filter flags narrow down the exported elements.
+
+With the format 'csv', the tables are written to elements.csv, interfaces.csv and
+dependencies.csv in the directory given by '--out'. With the format 'xlsx', a workbook with
+one sheet per table is written to the file given by '--out'.
+
+The formats 'graphml' and 'cytoscape' write the resolved dependency graph to the file given by
+'--out' instead, to be explored in tools like yEd, Gephi or Cytoscape. Elements and interfaces
+are nodes nested in the nodes of their parent elements, as nested graphs in GraphML and as
+compound nodes in Cytoscape.js JSON. Dependencies are edges, tags become attributes. The IDs of
+interfaces are prefixed with 'if:' and those of dependencies with 'dep:'.
+
+The format 'structurizr' writes a Structurizr DSL workspace with views to the file given by
+'--out'. Elements below the root become software systems or persons, containers and
+components, deeper elements are collapsed into their component. Interfaces, types, teams, tags
+and the original descriptions of relationships are kept as properties, so that the workspace
+can be imported again with 'import'.`,
+		Args: cobra.NoArgs,
+		Run:  a.exportCmd,
+	}
+	exportCmd.PersistentFlags().StringVar(&a.flags.export.format, "format", "csv", "export format (csv, xlsx, graphml, cytoscape or structurizr)")
+	exportCmd.PersistentFlags().StringVar(&a.flags.export.out, "out", "", "output directory for csv or output file for all other formats")
+	_ = exportCmd.MarkPersistentFlagRequired("out")
+	rootCmd.AddCommand(exportCmd)
+
+	// import
+	importCmd := &cobra.Command{
+		Use:   "import [format] [file]",
+		Short: "creates definitions from a model of another tool",
+		Long: `With the subcommand 'import', a definition is created in the base directory for every
+element of a model of another tool. The only supported format is 'structurizr', which reads the
+model of a Structurizr DSL workspace: persons and software systems become elements below the
+root element, containers and components become their children and relationships become
+dependencies. The properties written by 'export --format structurizr' are mapped back to
+interfaces, types, teams and tags. Views, styles and deployment environments are ignored.
+
+The base directory must not contain a definition of the root element yet.`,
+		Args: cobra.ExactArgs(2),
+		Run:  a.importCmd,
+	}
+	rootCmd.AddCommand(importCmd)
+
+	// mv
+	mvCmd := &cobra.Command{
+		Use:   "mv [old ID] [new ID]",
+		Short: "moves an element and rewrites all references to it",
+		Long: `With the subcommand 'mv', the directory of an element is moved or renamed so that the element
+gets the new ID. All dependencies and replacements referencing the element or its children are
+rewritten in the definition files, relative references stay relative. Everything else in the
+definition files, including the Markdown body, is preserved.
+
+The new parent element must exist. Elements can only be moved in a local base, not in a git
+repository. Use '--dry-run' to only report the changes.`,
+		Args: cobra.ExactArgs(2),
+		Run:  a.mvCmd,
+	}
+	mvCmd.PersistentFlags().StringVar(&a.flags.mv.format, "format", "table", "output format (table or json)")
+	mvCmd.PersistentFlags().BoolVar(&a.flags.mv.dryRun, "dry-run", false, "only report the changes")
+	rootCmd.AddCommand(mvCmd)
+
+	// init
+	initCmd := &cobra.Command{
+		Use:   "init",
+		Short: "creates a starter configuration and root element in the base directory",
+		Long: `With the subcommand 'init', a configuration file containing the built in renderer as
+renderer 'default' is created along with a definition of the root element in the base directory.
+Definitions are named after the first glob without wildcards.`,
+		Args: cobra.NoArgs,
+		Run:  a.initCmd,
+	}
+	rootCmd.AddCommand(initCmd)
+
+	// new
+	newCmd := &cobra.Command{
+		Use:   "new",
+		Short: "adds elements, interfaces and dependencies to the definitions",
+	}
+	newElementCmd := &cobra.Command{
+		Use:   "element [ID]",
+		Short: "adds an element below its existing parent",
+		Args:  cobra.ExactArgs(1),
+		Run:   a.newElementCmd,
+	}
+	newElementCmd.PersistentFlags().StringVar(&a.flags.new.name, "name", "", "name of the element")
+	newElementCmd.PersistentFlags().StringVar(&a.flags.new.elementType, "type", "", "type of the element")
+	newElementCmd.PersistentFlags().StringVar(&a.flags.new.owner, "owner", "", "team owning the element")
+	newCmd.AddCommand(newElementCmd)
+	newInterfaceCmd := &cobra.Command{
+		Use:   "interface [ID]",
+		Short: "adds an interface to an existing element",
+		Args:  cobra.ExactArgs(1),
+		Run:   a.newInterfaceCmd,
+	}
+	newInterfaceCmd.PersistentFlags().StringVar(&a.flags.new.description, "description", "", "description of the interface")
+	newInterfaceCmd.PersistentFlags().StringVar(&a.flags.new.protocol, "protocol", "", "protocol of the interface")
+	newInterfaceCmd.PersistentFlags().StringVar(&a.flags.new.version, "version", "", "version of the interface")
+	newCmd.AddCommand(newInterfaceCmd)
+	newDependencyCmd := &cobra.Command{
+		Use:   "dependency [ID]",
+		Short: "adds a dependency to an existing element",
+		Long: `With the subcommand 'new dependency', a dependency is added to an existing element. The
+reference given with '--on' is resolved relative to the element and must point to an existing
+interface or element.`,
+		Args: cobra.ExactArgs(1),
+		Run:  a.newDependencyCmd,
+	}
+	newDependencyCmd.PersistentFlags().StringVar(&a.flags.new.dependsOn, "on", "", "reference of the interface or element depended on")
+	newDependencyCmd.PersistentFlags().StringVar(&a.flags.new.description, "description", "", "description of the dependency")
+	newDependencyCmd.PersistentFlags().StringVar(&a.flags.new.kind, "kind", "", "kind of the dependency (sync, async, batch or manual)")
+	newDependencyCmd.PersistentFlags().StringVar(&a.flags.new.criticality, "criticality", "", "criticality of the dependency (hard or soft)")
+	_ = newDependencyCmd.MarkPersistentFlagRequired("on")
+	newCmd.AddCommand(newDependencyCmd)
+	rootCmd.AddCommand(newCmd)
+
+	// fmt
+	fmtCmd := &cobra.Command{
+		Use:   "fmt",
+		Short: "formats the YAML of all definitions canonically",
+		Long: `With the subcommand 'fmt', the YAML header of every Markdown definition and every YAML
+definition is rewritten canonically: keys are ordered as documented, maps are sorted, quotes
+are only used where required and everything is indented by two spaces. The Markdown body
+stays untouched, JSON and TOML definitions are not formatted.
+
+Use '--check' to only list unformatted files, the command then exits with a non-zero exit
+code if any file is not formatted.`,
+		Args: cobra.NoArgs,
+		Run:  a.fmtCmd,
+	}
+	fmtCmd.PersistentFlags().BoolVar(&a.flags.fmt.check, "check", false, "only list unformatted files")
+	rootCmd.AddCommand(fmtCmd)
+
+	// embed
+	embedCmd := &cobra.Command{
+		Use:   "embed",
+		Short: "embeds rendered diagrams between markers in the Markdown definitions",
+		Long: `With the subcommand 'embed', the content between the markers in the Markdown body of every
+definition is replaced by a freshly rendered diagram:
+
+  <!-- sysdoc:diagram focus=self renderer=mermaid -->
+  <!-- /sysdoc -->
+
+The output of the templates is embedded as code block, the language of the code block is the
+name of the postprocessor of the renderer, e.g. 'd2', unless it is set with 'lang'. With 'link', the diagram is rendered with
+the postprocessor to the given path relative to the definition and embedded as image.
+
+The attributes 'focus', 'query', 'owner', 'view' and 'depth' narrow down the elements as the
+global flags do. The focus is a comma separated list of references, where 'self' refers to
+the element of the definition. Without a renderer, the renderer 'default' is used.
+
+Use '--check' to only list outdated files, the command then exits with a non-zero exit code
+if any diagram is outdated.`,
+		Args: cobra.NoArgs,
+		Run:  a.embedCmd,
+	}
+	embedCmd.PersistentFlags().BoolVar(&a.flags.embed.check, "check", false, "only list files with outdated diagrams")
+	rootCmd.AddCommand(embedCmd)
+
 	// version
 	versionCmd := &cobra.Command{
 		Use:   "version",
@@ -105,7 +483,7 @@ called 'custom' using the following URL: http://localhost:8080/A.AB+C?renderer=c
 	return a
 }
 
-func (a *App) renderCmd(cmd *cobra.Command, args []string) {
+func (a *App) setupPersistence() (persistence.Persistence, error) {
 	var pc persistence.Config
 	pc.Filepath = a.flags.base
 	pc.Git.URL = a.flags.git.url
@@ -113,49 +491,129 @@ func (a *App) renderCmd(cmd *cobra.Command, args []string) {
 	pc.Git.Pass = a.flags.git.pass
 	pc.Git.Keyfile = a.flags.git.keyfile
 	p, err := persistence.New(pc)
-	exitOnErr(err)
+	if err != nil {
+		return p, err
+	}
 	err = p.CheckoutBranch(a.flags.git.branch)
+	return p, err
+}
+
+func (a *App) filter() filter {
+	return filter{
+		Focus: a.flags.focus,
+		Owner: a.flags.owner,
+		View:  a.flags.view,
+		Depth: a.flags.depth,
+	}
+}
+
+func (a *App) renderCmd(cmd *cobra.Command, args []string) {
+	p, err := a.setupPersistence()
 	exitOnErr(err)
 
-	cfg, err := NewConfig(a.flags.configfile, p.Filesystem())
+	if !a.flags.render.watch {
+		exitOnErr(a.render(p)...)
+		return
+	}
+	if a.flags.git.url != "" || a.flags.render.out == "" {
+		exitOnErr(fmt.Errorf("Watching requires a local base and '--out' to be set"))
+	}
+	interval, err := time.ParseDuration(a.flags.render.interval)
 	exitOnErr(err)
 
+	// the output must not trigger a new rendering if it is written below the base
+	skip := []string{}
+	base, err := filepath.Abs(a.flags.base)
+	exitOnErr(err)
+	out, err := filepath.Abs(a.flags.render.out)
+	exitOnErr(err)
+	if rel, err := filepath.Rel(base, out); err == nil {
+		skip = append(skip, filepath.Join(a.flags.base, rel))
+	}
+
+	watch(p.Filesystem(), a.flags.base, interval, skip, nil, func() {
+		errs := a.render(p)
+		for _, err := range errs {
+			fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+		}
+		if len(errs) == 0 {
+			fmt.Fprintf(os.Stderr, "Rendered '%s' at %s\n", a.flags.render.out, time.Now().Format("15:04:05"))
+		}
+	}, func(err error) {
+		fmt.Fprintf(os.Stderr, "ERROR: %s\n", err.Error())
+	})
+}
+
+// render loads the system and renders it to the output file or standard output
+func (a *App) render(p persistence.Persistence) []error {
+	cfg, err := NewConfig(a.flags.configfile, p.Filesystem())
+	if err != nil {
+		return []error{err}
+	}
+
 	// build system
-	sys, errs := NewSystem(a.flags.base, a.flags.glob, a.flags.focus, p)
-	exitOnErr(errs...)
+	sys, errs := NewSystem(a.flags.base, a.flags.glob, a.filter(), cfg, p)
+	if len(errs) > 0 {
+		return errs
+	}
 
 	// render template
 	renderer, ok := cfg.Renderer[a.flags.render.renderer]
 	if !ok {
-		exitOnErr(fmt.Errorf("renderer %s not specified in %s", a.flags.render.renderer, a.flags.configfile))
+		return []error{fmt.Errorf("renderer %s not specified in %s", a.flags.render.renderer, a.flags.configfile)}
 	}
 	data, err := a.renderer.Do(sys, renderer, a.flags.render.noPostprocess)
-	exitOnErr(err)
+	if err != nil {
+		return []error{err}
+	}
 
 	if a.flags.render.out != "" {
 		err = os.WriteFile(a.flags.render.out, data, 0644)
-		exitOnErr(err)
+		if err != nil {
+			return []error{err}
+		}
 	} else {
 		fmt.Println(string(data))
 	}
+	return nil
 }
 
-func (a *App) serveCmd(cmd *cobra.Command, args []string) {
-	var pc persistence.Config
-	pc.Filepath = a.flags.base
-	pc.Git.URL = a.flags.git.url
-	pc.Git.User = a.flags.git.user
-	pc.Git.Pass = a.flags.git.pass
-	pc.Git.Keyfile = a.flags.git.keyfile
-	p, err := persistence.New(pc)
+func (a *App) generateCmd(cmd *cobra.Command, args []string) {
+	p, err := a.setupPersistence()
 	exitOnErr(err)
-	err = p.CheckoutBranch(a.flags.git.branch)
+
+	cfg, err := NewConfig(a.flags.configfile, p.Filesystem())
+	exitOnErr(err)
+	if len(cfg.Outputs) == 0 {
+		exitOnErr(fmt.Errorf("No outputs declared in %s", a.flags.configfile))
+	}
+
+	for _, o := range cfg.Outputs {
+		if o.PerElement && o.Path == "" && a.flags.git.url != "" {
+			exitOnErr(fmt.Errorf("Diagrams can only be written next to the definitions in a local base"))
+		}
+	}
+
+	// build system
+	sys, errs := loadSystem(a.flags.base, a.flags.glob, cfg, p)
+	exitOnErr(errs...)
+
+	written, errs := a.renderer.generate(sys, cfg, p.Filesystem())
+	for _, path := range written {
+		fmt.Printf("Generated '%s'\n", path)
+	}
+	exitOnErr(errs...)
+}
+
+func (a *App) serveCmd(cmd *cobra.Command, args []string) {
+	p, err := a.setupPersistence()
 	exitOnErr(err)
 
 	s, err := NewServer(
 		a.flags.serve.listener,
 		a.flags.base,
 		a.flags.glob,
+		a.flags.configfile,
 		a.flags.serve.cacheTimeout,
 		p,
 		*a.renderer,
@@ -165,6 +623,312 @@ func (a *App) serveCmd(cmd *cobra.Command, args []string) {
 	exitOnErr(err)
 }
 
+func (a *App) statsCmd(cmd *cobra.Command, args []string) {
+	p, err := a.setupPersistence()
+	exitOnErr(err)
+
+	cfg, err := NewConfig(a.flags.configfile, p.Filesystem())
+	exitOnErr(err)
+
+	// build system
+	sys, errs := NewSystem(a.flags.base, a.flags.glob, a.filter(), cfg, p)
+	exitOnErr(errs...)
+
+	err = newStatsReport(sys).Write(os.Stdout, a.flags.stats.format, a.flags.stats.interfaces)
+	exitOnErr(err)
+}
+
+func (a *App) queryCmd(cmd *cobra.Command, args []string) {
+	q, err := newQuery(args[0])
+	exitOnErr(err)
+
+	p, err := a.setupPersistence()
+	exitOnErr(err)
+
+	cfg, err := NewConfig(a.flags.configfile, p.Filesystem())
+	exitOnErr(err)
+
+	// build system
+	sys, errs := NewSystem(a.flags.base, a.flags.glob, a.filter(), cfg, p)
+	exitOnErr(errs...)
+
+	elems := q.Select(sys)
+	if !a.flags.query.records {
+		for _, e := range elems {
+			fmt.Println(e.getID("."))
+		}
+		return
+	}
+	records := []queryRecord{}
+	for _, e := range elems {
+		records = append(records, newQueryRecord(e))
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	err = enc.Encode(records)
+	exitOnErr(err)
+}
+
+func (a *App) impactCmd(cmd *cobra.Command, args []string) {
+	p, err := a.setupPersistence()
+	exitOnErr(err)
+
+	cfg, err := NewConfig(a.flags.configfile, p.Filesystem())
+	exitOnErr(err)
+
+	// build system
+	sys, errs := NewSystem(a.flags.base, a.flags.glob, filter{}, cfg, p)
+	exitOnErr(errs...)
+
+	failing := []*element{}
+	for _, id := range args {
+		e, err := sys.findElementByPosition(positionFromID(id, "."))
+		exitOnErr(err)
+		failing = append(failing, e)
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tIMPACT")
+	for _, i := range sys.impactOf(failing) {
+		fmt.Fprintf(tw, "%s\t%s\n", i.ID, i.Impact)
+	}
+	err = tw.Flush()
+	exitOnErr(err)
+}
+
+func (a *App) lintCmd(cmd *cobra.Command, args []string) {
+	p, err := a.setupPersistence()
+	exitOnErr(err)
+
+	cfg, err := NewConfig(a.flags.configfile, p.Filesystem())
+	exitOnErr(err)
+
+	// build system
+	sys, errs := NewSystem(a.flags.base, a.flags.glob, filter{}, cfg, p)
+	exitOnErr(errs...)
+
+	warnings := sys.lint(time.Now())
+	for _, w := range warnings {
+		fmt.Fprintf(os.Stderr, "WARNING: %s\n", w)
+	}
+	if len(warnings) > 0 {
+		os.Exit(1)
+	}
+}
+
+func (a *App) ownersCmd(cmd *cobra.Command, args []string) {
+	p, err := a.setupPersistence()
+	exitOnErr(err)
+
+	cfg, err := NewConfig(a.flags.configfile, p.Filesystem())
+	exitOnErr(err)
+
+	// build system
+	sys, errs := NewSystem(a.flags.base, a.flags.glob, filter{}, cfg, p)
+	exitOnErr(errs...)
+
+	err = newOwnersReport(sys, a.flags.owners.team, a.flags.owners.crossTeam).Write(os.Stdout, a.flags.owners.format)
+	exitOnErr(err)
+}
+
+func (a *App) reportCmd(cmd *cobra.Command, args []string) {
+	p, err := a.setupPersistence()
+	exitOnErr(err)
+
+	cfg, err := NewConfig(a.flags.configfile, p.Filesystem())
+	exitOnErr(err)
+
+	// build system
+	sys, errs := NewSystem(a.flags.base, a.flags.glob, a.filter(), cfg, p)
+	exitOnErr(errs...)
+
+	err = newReport(sys, time.Now()).Write(os.Stdout, a.flags.report.format)
+	exitOnErr(err)
+}
+
+func (a *App) exportCmd(cmd *cobra.Command, args []string) {
+	if !contains(exportFormats, a.flags.export.format) {
+		exitOnErr(fmt.Errorf("Export format '%s' is not supported, please choose one of %v", a.flags.export.format, exportFormats))
+	}
+	p, err := a.setupPersistence()
+	exitOnErr(err)
+
+	cfg, err := NewConfig(a.flags.configfile, p.Filesystem())
+	exitOnErr(err)
+
+	// build system, Structurizr knows three levels of elements only
+	f := a.filter()
+	if a.flags.export.format == "structurizr" && (f.Depth == 0 || f.Depth > len(structurizrKinds)) {
+		f.Depth = len(structurizrKinds)
+	}
+	sys, errs := NewSystem(a.flags.base, a.flags.glob, f, cfg, p)
+	exitOnErr(errs...)
+
+	switch {
+	case a.flags.export.format == "structurizr":
+		err = writeStructurizrExport(sys, a.flags.export.out)
+	case contains(graphFormats, a.flags.export.format):
+		err = writeGraphExport(newGraph(sys, time.Now()), a.flags.export.format, a.flags.export.out)
+	default:
+		err = writeExport(exportTables(sys, time.Now()), a.flags.export.format, a.flags.export.out)
+	}
+	exitOnErr(err)
+}
+
+func (a *App) importCmd(cmd *cobra.Command, args []string) {
+	if args[0] != "structurizr" {
+		exitOnErr(fmt.Errorf("Import format '%s' is not supported, please choose one of [structurizr]", args[0]))
+	}
+	if a.flags.git.url != "" {
+		exitOnErr(fmt.Errorf("Definitions can only be imported into a local base"))
+	}
+	data, err := os.ReadFile(args[1])
+	exitOnErr(err)
+
+	p, err := a.setupPersistence()
+	exitOnErr(err)
+
+	created, err := importStructurizr(data, a.flags.base, a.flags.glob, p.Filesystem())
+	for _, file := range created {
+		fmt.Printf("Created '%s'\n", file)
+	}
+	exitOnErr(err)
+}
+
+func (a *App) mvCmd(cmd *cobra.Command, args []string) {
+	if a.flags.git.url != "" {
+		exitOnErr(fmt.Errorf("Elements can only be moved in a local base"))
+	}
+	p, err := a.setupPersistence()
+	exitOnErr(err)
+
+	cfg, err := NewConfig(a.flags.configfile, p.Filesystem())
+	exitOnErr(err)
+
+	// build system
+	sys, errs := NewSystem(a.flags.base, a.flags.glob, filter{}, cfg, p)
+	exitOnErr(errs...)
+
+	r, err := sys.move(args[0], args[1], p.Filesystem(), a.flags.mv.dryRun)
+	exitOnErr(err)
+
+	err = r.Write(os.Stdout, a.flags.mv.format)
+	exitOnErr(err)
+}
+
+func (a *App) initCmd(cmd *cobra.Command, args []string) {
+	if a.flags.git.url != "" {
+		exitOnErr(fmt.Errorf("A base can only be initialized locally"))
+	}
+	p, err := a.setupPersistence()
+	exitOnErr(err)
+
+	created, err := initBase(a.flags.base, a.flags.configfile, a.flags.glob, p.Filesystem())
+	exitOnErr(err)
+	for _, file := range created {
+		fmt.Printf("Created '%s'\n", file)
+	}
+}
+
+// loadLocal loads the full system from a local base to be edited
+func (a *App) loadLocal() (*element, config, persistence.Persistence) {
+	if a.flags.git.url != "" {
+		exitOnErr(fmt.Errorf("Definitions can only be edited in a local base"))
+	}
+	p, err := a.setupPersistence()
+	exitOnErr(err)
+
+	cfg, err := NewConfig(a.flags.configfile, p.Filesystem())
+	exitOnErr(err)
+
+	// build system
+	sys, errs := NewSystem(a.flags.base, a.flags.glob, filter{}, cfg, p)
+	exitOnErr(errs...)
+	return sys, cfg, p
+}
+
+func (a *App) newElementCmd(cmd *cobra.Command, args []string) {
+	sys, cfg, p := a.loadLocal()
+
+	if _, ok := cfg.Types.viewOf(a.flags.new.elementType); a.flags.new.elementType != "" && !ok {
+		exitOnErr(fmt.Errorf("Type '%s' is not valid, please choose one of %v", a.flags.new.elementType, cfg.Types.known()))
+	}
+	definition := mappingNode("name", a.flags.new.name, "type", a.flags.new.elementType)
+	if a.flags.new.owner != "" {
+		definition.Content = append(definition.Content, nestedNode([]string{"owner"}, mappingNode("team", a.flags.new.owner)).Content...)
+	}
+	file, err := sys.addElement(args[0], a.flags.glob, definition, p.Filesystem())
+	exitOnErr(err)
+	fmt.Printf("Added element '%s' to '%s'\n", args[0], file)
+}
+
+func (a *App) newInterfaceCmd(cmd *cobra.Command, args []string) {
+	sys, _, p := a.loadLocal()
+
+	definition := mappingNode("description", a.flags.new.description, "protocol", a.flags.new.protocol, "version", a.flags.new.version)
+	file, err := sys.addInterface(args[0], a.flags.glob, definition, p.Filesystem())
+	exitOnErr(err)
+	fmt.Printf("Added interface '%s' to '%s'\n", args[0], file)
+}
+
+func (a *App) newDependencyCmd(cmd *cobra.Command, args []string) {
+	sys, _, p := a.loadLocal()
+
+	c := dependencyConfiguration{Kind: a.flags.new.kind, Criticality: a.flags.new.criticality}
+	exitOnErr(c.validate())
+	definition := mappingNode("depends_on", a.flags.new.dependsOn, "description", a.flags.new.description, "kind", a.flags.new.kind, "criticality", a.flags.new.criticality)
+	file, err := sys.addDependency(args[0], a.flags.new.dependsOn, a.flags.glob, definition, p.Filesystem())
+	exitOnErr(err)
+	fmt.Printf("Added dependency '%s' to '%s'\n", args[0], file)
+}
+
+func (a *App) fmtCmd(cmd *cobra.Command, args []string) {
+	if a.flags.git.url != "" && !a.flags.fmt.check {
+		exitOnErr(fmt.Errorf("Definitions can only be formatted in a local base, use '--check' for git repositories"))
+	}
+	p, err := a.setupPersistence()
+	exitOnErr(err)
+
+	cfg, err := NewConfig(a.flags.configfile, p.Filesystem())
+	exitOnErr(err)
+
+	sys, err := newElementFromPersistence(a.flags.base, a.flags.glob, cfg, p.Filesystem())
+	exitOnErr(err)
+
+	changed, err := sys.formatDefinitions(p.Filesystem(), a.flags.fmt.check)
+	exitOnErr(err)
+	for _, file := range changed {
+		fmt.Println(file)
+	}
+	if a.flags.fmt.check && len(changed) > 0 {
+		os.Exit(1)
+	}
+}
+
+func (a *App) embedCmd(cmd *cobra.Command, args []string) {
+	if a.flags.git.url != "" && !a.flags.embed.check {
+		exitOnErr(fmt.Errorf("Diagrams can only be embedded in a local base, use '--check' for git repositories"))
+	}
+	p, err := a.setupPersistence()
+	exitOnErr(err)
+
+	cfg, err := NewConfig(a.flags.configfile, p.Filesystem())
+	exitOnErr(err)
+
+	// build system
+	sys, errs := loadSystem(a.flags.base, a.flags.glob, cfg, p)
+	exitOnErr(errs...)
+
+	changed, err := a.renderer.embedDiagrams(sys, cfg, p.Filesystem(), a.flags.embed.check)
+	exitOnErr(err)
+	for _, file := range changed {
+		fmt.Println(file)
+	}
+	if a.flags.embed.check && len(changed) > 0 {
+		os.Exit(1)
+	}
+}
+
 func (a *App) versionCmd(cmd *cobra.Command, args []string) {
 	fmt.Println("Version:   ", versioninfo.Version)
 	fmt.Println("Revision:  ", versioninfo.Revision)
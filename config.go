@@ -2,7 +2,9 @@ package main
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"io/fs"
 
 	"github.com/go-git/go-billy/v5"
 	"github.com/mitchellh/go-homedir"
@@ -11,6 +13,14 @@ import (
 
 type config struct {
 	Renderer map[string]renderConfig `yaml:"renderers"`
+	Tags     tagsConfig              `yaml:"tags"`
+	Schema   schemaConfig            `yaml:"schema"`
+	Types    typesConfig             `yaml:"types"`
+	Outputs  []outputConfig          `yaml:"outputs"`
+	// SkipEmptyDirectories only creates elements for directories with a definition file
+	SkipEmptyDirectories bool `yaml:"skip_empty_directories"`
+
+	path string
 }
 
 func NewConfig(path string, filesys billy.Filesystem) (config, error) {
@@ -19,6 +29,12 @@ func NewConfig(path string, filesys billy.Filesystem) (config, error) {
 	if err != nil {
 		return c, err
 	}
+	c.path = path
+
+	// the configuration file is optional, the defaults are used if it does not exist
+	if _, err := filesys.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	}
 
 	file, err := filesys.Open(path)
 	if err != nil {
@@ -37,6 +53,23 @@ func NewConfig(path string, filesys billy.Filesystem) (config, error) {
 		return c, fmt.Errorf("could not read data from config file %s: %s", path, err.Error())
 	}
 
+	err = c.Schema.validate()
+	if err != nil {
+		return c, fmt.Errorf("schema in config file %s is not valid: %s", path, err.Error())
+	}
+
+	err = c.Types.validate()
+	if err != nil {
+		return c, fmt.Errorf("types in config file %s are not valid: %s", path, err.Error())
+	}
+
+	for n, o := range c.Outputs {
+		err = o.validate(c.Renderer)
+		if err != nil {
+			return c, fmt.Errorf("output %d in config file %s is not valid: %s", n+1, path, err.Error())
+		}
+	}
+
 	return c, nil
 }
 
@@ -1,43 +1,113 @@
 package main
 
 import (
-	"strings"
+	"fmt"
+)
+
+var (
+	dependencyKinds         = []string{"sync", "async", "batch", "manual"}
+	dependencyCriticalities = []string{"hard", "soft"}
 )
 
 type dependencyConfiguration struct {
 	DependsOn   string            `yaml:"depends_on" json:"depends_on"`
 	Description string            `yaml:"description" json:"description"`
+	Kind        string            `yaml:"kind" json:"kind"`
+	Criticality string            `yaml:"criticality" json:"criticality"`
+	Protocol    string            `yaml:"protocol" json:"protocol"`
+	Version     string            `yaml:"version" json:"version"`
 	Tags        map[string]string `yaml:"tags" json:"tags"`
 }
 
+func (c dependencyConfiguration) validate() error {
+	if c.Kind != "" && !contains(dependencyKinds, c.Kind) {
+		return fmt.Errorf("Kind '%s' is not valid, please choose one of %v", c.Kind, dependencyKinds)
+	}
+	if c.Criticality != "" && !contains(dependencyCriticalities, c.Criticality) {
+		return fmt.Errorf("Criticality '%s' is not valid, please choose one of %v", c.Criticality, dependencyCriticalities)
+	}
+	return nil
+}
+
 type dependency struct {
-	fragment    string
-	description string
-	reference   string
-	tags        map[string]string
+	fragment     string
+	description  string
+	reference    string
+	kind         string
+	criticality  string
+	protocol     string
+	version      string
+	declaredTags map[string]string
+	tags         map[string]string
 
-	dependsOn      *interf
-	viaPropagation *interf
-	belongsTo      *element
-	k              bool
+	dependsOn        *interf
+	dependsOnElement *element
+	viaPropagation   *interf
+	belongsTo        *element
+	k                bool
 }
 
 func newDependency(fragment string, c dependencyConfiguration, e *element) *dependency {
-	return &dependency{
-		fragment:    fragment,
-		description: c.Description,
-		reference:   c.DependsOn,
-		tags:        c.Tags,
-		belongsTo:   e,
+	d := &dependency{
+		fragment:     fragment,
+		description:  c.Description,
+		reference:    c.DependsOn,
+		kind:         c.Kind,
+		criticality:  c.Criticality,
+		protocol:     c.Protocol,
+		version:      c.Version,
+		declaredTags: c.Tags,
+		tags:         c.Tags,
+		belongsTo:    e,
 	}
-}
-
-func (d *dependency) positionFromReference() []string {
-	return strings.Split(d.reference, ".")
+	// dependencies used to be marked as manual with a tag
+	if d.kind == "" && d.tags["manual"] != "" {
+		d.kind = "manual"
+	}
+	if d.kind == "" {
+		d.kind = "sync"
+	}
+	if d.criticality == "" {
+		d.criticality = "hard"
+	}
+	return d
 }
 
 func (d *dependency) keep() {
 	d.k = true
-	d.dependsOn.keep()
+	if d.dependsOn != nil {
+		d.dependsOn.keep()
+	} else {
+		d.dependsOnElement.keep()
+	}
 	d.belongsTo.keep()
 }
+
+// provider returns the element the dependency points to, either directly or through one
+// of its interfaces, or nil if the dependency is not resolved
+func (d *dependency) provider() *element {
+	if d.dependsOn != nil {
+		return d.dependsOn.belongsTo
+	}
+	return d.dependsOnElement
+}
+
+// providedInterfaces returns the interface the dependency points to, dependencies on a whole
+// element depend on all of its interfaces
+func (d *dependency) providedInterfaces() []*interf {
+	if d.dependsOn != nil {
+		return []*interf{d.dependsOn}
+	}
+	if d.dependsOnElement != nil {
+		return d.dependsOnElement.interfaces
+	}
+	return []*interf{}
+}
+
+// targetID returns the ID of the interface or element the dependency points to
+func (d *dependency) targetID(sep string) string {
+	if d.dependsOn != nil {
+		return d.dependsOn.getID(sep)
+	}
+	return d.dependsOnElement.getID(sep)
+}
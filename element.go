@@ -2,109 +2,303 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
 	"sysdoc/internal/persistence"
 
+	"github.com/BurntSushi/toml"
 	"github.com/adrg/frontmatter"
 	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+	"gopkg.in/yaml.v3"
 )
 
-func NewSystem(basedir, glob string, focus []string, p persistence.Persistence) (*element, []error) {
-	sys, err := newElementFromPersistence(basedir, glob, p.Filesystem())
+// filter narrows down the elements of a system, all fields are optional
+type filter struct {
+	// Focus lists the IDs of the elements to focus on
+	Focus []string
+	// Query focuses on all elements matching the query expression
+	Query string
+	// Owner focuses on all elements owned by the team
+	Owner string
+	// View is the C4 view (context, container or component) to be shown
+	View string
+	// Depth collapses all elements nested deeper than depth, 0 shows all elements
+	Depth int
+}
+
+const ignoreFile = ".sysdocignore"
+
+func NewSystem(basedir string, globs []string, f filter, cfg config, p persistence.Persistence) (*element, []error) {
+	sys, errs := loadSystem(basedir, globs, cfg, p)
+	if len(errs) > 0 {
+		return sys, errs
+	}
+	err := sys.apply(f, cfg)
+	if err != nil {
+		return sys, []error{err}
+	}
+	return sys, nil
+}
+
+// loadSystem loads, checks and resolves the system without applying any filter
+func loadSystem(basedir string, globs []string, cfg config, p persistence.Persistence) (*element, []error) {
+	sys, err := newElementFromPersistence(basedir, globs, cfg, p.Filesystem())
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return sys, joined.Unwrap()
+	}
 	if err != nil {
 		return sys, []error{err}
 	}
 
-	errs := sys.resolveDependencies(sys)
+	sys.applyTags(cfg.Tags, map[string]string{})
+
+	errs := cfg.Schema.checkRequired(sys)
+	errs = append(errs, cfg.Types.check(sys)...)
 	if len(errs) > 0 {
 		return sys, errs
 	}
 
+	errs = sys.resolveDependencies(sys)
+	if len(errs) > 0 {
+		return sys, errs
+	}
+
+	sys.calculateMetrics()
+	return sys, nil
+}
+
+// apply narrows down a loaded system according to the filter and propagates the interfaces,
+// it must only be called once per system
+func (sys *element) apply(f filter, cfg config) error {
+	focus := f.Focus
+	if f.Query != "" {
+		q, err := newQuery(f.Query)
+		if err != nil {
+			return err
+		}
+		selected := q.Select(sys)
+		if len(selected) == 0 {
+			return fmt.Errorf("No elements match query '%s'", f.Query)
+		}
+		for _, e := range selected {
+			focus = append(focus, e.getID("."))
+		}
+	}
+	if f.Owner != "" {
+		owned := sys.ownedBy(f.Owner)
+		if len(owned) == 0 {
+			return fmt.Errorf("No elements owned by team '%s' found", f.Owner)
+		}
+		focus = append(focus, owned...)
+	}
+
 	if len(focus) > 0 {
-		err = sys.focus(focus)
+		err := sys.focus(focus)
 		if err != nil {
-			return sys, []error{err}
+			return err
 		}
 	}
 
-	err = sys.propagateInterfaces()
-	if err != nil {
-		return sys, []error{err}
+	if f.View != "" {
+		err := sys.view(f.View, cfg.Types)
+		if err != nil {
+			return err
+		}
 	}
-	return sys, nil
+
+	if f.Depth > 0 {
+		sys.limitDepth(f.Depth)
+	}
+
+	return sys.propagateInterfaces()
 }
 
 type elementConfiguration struct {
 	Name         string                             `yaml:"name" json:"name"`
+	Alias        string                             `yaml:"alias" json:"alias"`
+	Type         string                             `yaml:"type" json:"type"`
 	Tags         map[string]string                  `yaml:"tags" json:"tags"`
+	Owner        owner                              `yaml:"owner" json:"owner"`
+	Fields       map[string]interface{}             `yaml:"fields" json:"fields"`
 	Dependencies map[string]dependencyConfiguration `yaml:"dependencies" json:"dependencies"`
 	Interfaces   map[string]interfConfiguration     `yaml:"interfaces" json:"interfaces"`
+	Children     map[string]elementConfiguration    `yaml:"children" json:"children"`
+	DocFile      string                             `yaml:"doc" json:"doc"`
 	Doc          []byte                             `yaml:"-" json:"-"`
+	// DefaultInterface is used for dependencies on the element itself
+	DefaultInterface string `yaml:"default_interface" json:"default_interface"`
 }
 
-func newElementConfigurationFromFile(path string, filesys billy.Filesystem) (elementConfiguration, error) {
+// newElementConfigurationFromFile reads an element configuration from a file. Depending on
+// the extension, the file is parsed as plain YAML, JSON or TOML, in which case the 'doc' key
+// can point to a companion Markdown file relative to the file. Files with any other extension
+// are parsed as Markdown with a frontmatter header.
+func newElementConfigurationFromFile(path string, s schemaConfig, filesys billy.Filesystem) (elementConfiguration, error) {
 	ec := elementConfiguration{}
+	data, err := readFile(path, filesys)
+	if err != nil {
+		return ec, err
+	}
+	plain := true
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &ec)
+	case ".json":
+		err = json.Unmarshal(data, &ec)
+	case ".toml":
+		// decode to a map first to respect the yaml field names
+		m := map[string]interface{}{}
+		err = toml.Unmarshal(data, &m)
+		if err == nil {
+			data, err = yaml.Marshal(m)
+		}
+		if err == nil {
+			err = yaml.Unmarshal(data, &ec)
+		}
+	default:
+		plain = false
+		ec.Doc, err = frontmatter.Parse(bytes.NewReader(data), &ec)
+	}
+	if err != nil {
+		err = fmt.Errorf("Could not parse data of '%s', error occured: %w", path, err)
+		return ec, err
+	}
+	if plain && ec.DocFile != "" {
+		ec.Doc, err = readFile(filepath.Join(filepath.Dir(path), ec.DocFile), filesys)
+		if err != nil {
+			return ec, fmt.Errorf("Could not read doc of '%s': %w", path, err)
+		}
+	}
+	err = ec.validate(s)
+	if err != nil {
+		return ec, fmt.Errorf("Data of '%s' is invalid: %w", path, err)
+	}
+	return ec, err
+}
+
+// validate checks the dependencies, interfaces and inline children of the configuration
+// as well as the tags and fields against the schema
+func (ec elementConfiguration) validate(s schemaConfig) error {
+	if strings.ContainsAny(ec.Alias, ".@/") {
+		return fmt.Errorf("Alias '%s' is invalid: it must not contain '.', '@' or '/'", ec.Alias)
+	}
+	if _, ok := ec.Interfaces[ec.DefaultInterface]; ec.DefaultInterface != "" && !ok {
+		return fmt.Errorf("Default interface '%s' is not defined, please choose one of %v", ec.DefaultInterface, sortedKeys(ec.Interfaces))
+	}
+	for _, key := range sortedKeys(ec.Dependencies) {
+		err := ec.Dependencies[key].validate()
+		if err != nil {
+			return fmt.Errorf("Dependency '%s' is invalid: %w", key, err)
+		}
+	}
+	for _, key := range sortedKeys(ec.Interfaces) {
+		err := ec.Interfaces[key].validate()
+		if err != nil {
+			return fmt.Errorf("Interface '%s' is invalid: %w", key, err)
+		}
+	}
+	err := s.validateConfiguration(ec)
+	if err != nil {
+		return fmt.Errorf("Data does not match the schema: %w", err)
+	}
+	for _, key := range sortedKeys(ec.Children) {
+		if strings.Contains(key, ".") {
+			return fmt.Errorf("Child '%s' is invalid: the name must not contain '.'", key)
+		}
+		err := ec.Children[key].validate(s)
+		if err != nil {
+			return fmt.Errorf("Child '%s' is invalid: %w", key, err)
+		}
+	}
+	return nil
+}
+
+func readFile(path string, filesys billy.Filesystem) ([]byte, error) {
 	info, err := filesys.Stat(path)
 	if err != nil {
 		err = fmt.Errorf("Could not stat file '%s', error occured: %w", path, err)
-		return ec, err
+		return nil, err
 	}
 	if info.IsDir() {
-		return ec, fmt.Errorf("'%s' is a directory, but a file is expected", path)
+		return nil, fmt.Errorf("'%s' is a directory, but a file is expected", path)
 	}
 	file, err := filesys.Open(path)
 	if err != nil {
 		err = fmt.Errorf("Could not open file '%s', error occured: %w", path, err)
-		return ec, err
+		return nil, err
 	}
 	defer file.Close()
 	b := new(bytes.Buffer)
 	_, err = b.ReadFrom(file)
 	if err != nil {
 		err = fmt.Errorf("Could not read data of '%s', error occured: %w", path, err)
-		return ec, err
-	}
-	doc, err := frontmatter.Parse(b, &ec)
-	if err != nil {
-		err = fmt.Errorf("Could not parse data of '%s', error occured: %w", path, err)
-		return ec, err
+		return nil, err
 	}
-	ec.Doc = doc
-	return ec, err
+	return b.Bytes(), nil
 }
 
 type element struct {
 	// configured values
-	fragment string
-	name     string
-	tags     map[string]string
-	doc      []byte
+	fragment     string
+	name         string
+	alias        string
+	defaultIntf  string
+	elementType  string
+	declaredTags map[string]string
+	owner        owner
+	fields       map[string]interface{}
+	doc          []byte
+
+	// source of the definition, empty for inline children
+	dir  string
+	file string
 
 	// calculated values
+	tags         map[string]string
 	dependencies []*dependency
 	interfaces   []*interf
 	propagations []*interf
 	children     []*element
 	parent       *element
+	metrics      *elementMetrics
 	k            bool
 }
 
 func newElement(fragment string, c elementConfiguration) *element {
 	e := &element{
-		fragment: fragment,
-		name:     c.Name,
-		tags:     c.Tags,
-		doc:      c.Doc,
-	}
-	for key, dep := range c.Dependencies {
-		e.dependencies = append(e.dependencies, newDependency(key, dep, e))
-	}
-	for key, interf := range c.Interfaces {
-		e.interfaces = append(e.interfaces, newInterf(key, interf, e))
+		fragment:     fragment,
+		name:         c.Name,
+		alias:        c.Alias,
+		defaultIntf:  c.DefaultInterface,
+		elementType:  c.Type,
+		declaredTags: c.Tags,
+		tags:         c.Tags,
+		owner:        c.Owner,
+		fields:       c.Fields,
+		doc:          c.Doc,
+	}
+	// persons used to be marked with a tag
+	if e.elementType == "" && c.Tags["type"] == "user" {
+		e.elementType = "person"
+	}
+	for _, key := range sortedKeys(c.Dependencies) {
+		e.dependencies = append(e.dependencies, newDependency(key, c.Dependencies[key], e))
+	}
+	for _, key := range sortedKeys(c.Interfaces) {
+		e.interfaces = append(e.interfaces, newInterf(key, c.Interfaces[key], e))
+	}
+	// children can be defined inline in addition to the directory structure
+	for _, key := range sortedKeys(c.Children) {
+		child := newElement(key, c.Children[key])
+		child.parent = e
+		e.children = append(e.children, child)
 	}
 	return e
 }
@@ -133,6 +327,44 @@ func getPosition(basepath, path string) []string {
 	return pos
 }
 
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
+
+func indexOf(list []string, s string) int {
+	for i, l := range list {
+		if l == s {
+			return i
+		}
+	}
+	return -1
+}
+
+// readIgnoreFile reads the gitignore style patterns of the '.sysdocignore' file in the
+// directory if present
+func readIgnoreFile(dir string, domain []string, filesys billy.Filesystem) ([]gitignore.Pattern, error) {
+	patterns := []gitignore.Pattern{}
+	data, err := readFile(filepath.Join(dir, ignoreFile), filesys)
+	if errors.Is(err, fs.ErrNotExist) {
+		return patterns, nil
+	} else if err != nil {
+		return patterns, err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if strings.HasPrefix(line, "#") || strings.TrimSpace(line) == "" {
+			continue
+		}
+		patterns = append(patterns, gitignore.ParsePattern(line, domain))
+	}
+	return patterns, nil
+}
+
 func isHidden(f os.FileInfo) bool {
 	base := filepath.Base(f.Name())
 	if base == "." {
@@ -144,7 +376,10 @@ func isHidden(f os.FileInfo) bool {
 	return false
 }
 
-func newElementFromPersistence(basepath, matcher string, filesys billy.Filesystem) (*element, error) {
+// newElementFromPersistence reads the definitions below basepath. In every directory, the file
+// matching the first of the globs is used as definition of the directory. Files and directories
+// can be excluded with gitignore style patterns in '.sysdocignore' files.
+func newElementFromPersistence(basepath string, globs []string, cfg config, filesys billy.Filesystem) (*element, error) {
 	basepath = filepath.Clean(basepath)
 	_, err := filesys.Stat(basepath)
 	if err != nil {
@@ -153,8 +388,11 @@ func newElementFromPersistence(basepath, matcher string, filesys billy.Filesyste
 
 	// read all configuration files
 	configs := map[string]elementConfiguration{}
-	var walk func(string, string, billy.Filesystem) error
-	walk = func(path, matcher string, filesys billy.Filesystem) error {
+	definitions := map[string]string{}
+	// invalid definitions are collected to report all of them at once
+	invalid := []error{}
+	var walk func(string, []gitignore.Pattern) error
+	walk = func(path string, ignore []gitignore.Pattern) error {
 		info, err := filesys.Stat(path)
 		if err != nil {
 			err = fmt.Errorf("Could not stat file '%s', error occured: %w", path, err)
@@ -164,40 +402,77 @@ func newElementFromPersistence(basepath, matcher string, filesys billy.Filesyste
 			return nil
 		}
 		if info.IsDir() {
+			patterns, err := readIgnoreFile(path, getPosition(basepath, path), filesys)
+			if err != nil {
+				return err
+			}
+			ignore = append(ignore, patterns...)
+			matcher := gitignore.NewMatcher(ignore)
+
 			elems, err := filesys.ReadDir(path)
 			if err != nil {
 				return err
 			}
-			// currently sysdoc assumes that every directory is a "element". If no README in present, an "empty config" is used
-			handled := false
+			// every directory is an element unless empty directories are skipped. If no definition is present, an "empty config" is used
+			matches := make([][]string, len(globs))
 			for _, elem := range elems {
+				file := filepath.Join(path, elem.Name())
+				if matcher.Match(getPosition(basepath, file), elem.IsDir()) {
+					continue
+				}
 				if elem.IsDir() {
-					next := filepath.Join(path, elem.Name())
-					err = walk(next, matcher, filesys)
+					err = walk(file, ignore)
 					if err != nil {
 						return err
 					}
+					continue
 				}
-				if match, _ := filepath.Match(matcher, elem.Name()); match {
-					c, err := newElementConfigurationFromFile(filepath.Join(path, elem.Name()), filesys)
-					if err != nil {
-						return err
+				// the configuration file must not be read as definition if it matches as well
+				if cfg.path != "" && file == filepath.Clean(cfg.path) {
+					continue
+				}
+				for i, glob := range globs {
+					if match, _ := filepath.Match(glob, elem.Name()); match {
+						matches[i] = append(matches[i], file)
+						break
 					}
-					configs[path] = c
-					handled = true
 				}
 			}
-			if !handled {
-				configs[path] = elementConfiguration{}
+			// only the glob with the highest precedence which matches decides the definition
+			definition := ""
+			for i, files := range matches {
+				if len(files) > 1 {
+					return fmt.Errorf("Both '%s' and '%s' match '%s', only one definition per directory is allowed", files[0], files[1], globs[i])
+				}
+				if len(files) == 1 {
+					definition = files[0]
+					break
+				}
+			}
+			if definition == "" && cfg.SkipEmptyDirectories && path != basepath {
+				return nil
+			}
+			configs[path] = elementConfiguration{}
+			if definition != "" {
+				c, err := newElementConfigurationFromFile(definition, cfg.Schema, filesys)
+				if err != nil {
+					invalid = append(invalid, err)
+					return nil
+				}
+				configs[path] = c
+				definitions[path] = definition
 			}
 			return nil
 		}
 		return nil
 	}
-	err = walk(basepath, matcher, filesys)
+	err = walk(basepath, []gitignore.Pattern{})
 	if err != nil {
 		return nil, fmt.Errorf("could not walk '%s': %w", basepath, err)
 	}
+	if len(invalid) > 0 {
+		return nil, errors.Join(invalid...)
+	}
 
 	// generate element tree from configurations
 	e := &element{}
@@ -210,12 +485,13 @@ func newElementFromPersistence(basepath, matcher string, filesys billy.Filesyste
 	sort.Strings(keys)
 	// create elements and add to tree
 	for _, k := range keys {
-		pos := getPosition(basepath, k)
+		pos := definedPosition(basepath, k, configs)
 		fragment := "root"
 		if len(pos) > 0 {
 			fragment = pos[len(pos)-1]
 		}
 		elem := newElement(fragment, configs[k])
+		elem.dir, elem.file = k, definitions[k]
 		if !inited {
 			e = elem
 			inited = true
@@ -230,10 +506,78 @@ func newElementFromPersistence(basepath, matcher string, filesys billy.Filesyste
 	return e, nil
 }
 
+// definedPosition returns the position of path where directories without a configuration
+// are skipped, so that elements are attached to their nearest defined ancestor
+func definedPosition(basepath, path string, configs map[string]elementConfiguration) []string {
+	pos := []string{}
+	dir := basepath
+	for _, fragment := range getPosition(basepath, path) {
+		dir = filepath.Join(dir, fragment)
+		if _, ok := configs[dir]; ok {
+			pos = append(pos, fragment)
+		}
+	}
+	return pos
+}
+
+// clone returns a deep copy of the element tree, so that filters can be applied to the copy
+// without altering the original
+func (root *element) clone() *element {
+	elements := map[*element]*element{}
+	interfaces := map[*interf]*interf{}
+	for _, e := range root.getElements() {
+		c := *e
+		c.tags = map[string]string{}
+		for key, value := range e.tags {
+			c.tags[key] = value
+		}
+		elements[e] = &c
+		for _, i := range append(append([]*interf{}, e.interfaces...), e.propagations...) {
+			ci := *i
+			interfaces[i] = &ci
+		}
+	}
+	for e, c := range elements {
+		c.parent = elements[e.parent]
+		c.children = []*element{}
+		for _, child := range e.children {
+			c.children = append(c.children, elements[child])
+		}
+		c.interfaces = []*interf{}
+		for _, i := range e.interfaces {
+			c.interfaces = append(c.interfaces, interfaces[i])
+		}
+		c.propagations = []*interf{}
+		for _, i := range e.propagations {
+			c.propagations = append(c.propagations, interfaces[i])
+		}
+		c.dependencies = []*dependency{}
+		for _, d := range e.dependencies {
+			cd := *d
+			cd.belongsTo = c
+			cd.dependsOn = interfaces[d.dependsOn]
+			cd.dependsOnElement = elements[d.dependsOnElement]
+			cd.viaPropagation = interfaces[d.viaPropagation]
+			c.dependencies = append(c.dependencies, &cd)
+		}
+	}
+	for i, c := range interfaces {
+		c.belongsTo = elements[i.belongsTo]
+		c.propagates = interfaces[i.propagates]
+		c.replacedBy = interfaces[i.replacedBy]
+	}
+	return elements[root]
+}
+
 // appendAt adds a child element on the root element, where the path indicates each fragment of
 // the parent elements and the element itself
 func (e *element) appendAt(a *element, pos []string) error {
 	if len(pos) == 1 {
+		for _, child := range e.children {
+			if child.fragment == pos[0] {
+				return fmt.Errorf("Element '%s' is defined more than once", strings.Join(append(e.position(), pos[0]), "/"))
+			}
+		}
 		e.children = append(e.children, a)
 		a.parent = e
 		return nil
@@ -301,24 +645,90 @@ func (e *element) findInterfaceByPosition(pos []string) (*interf, error) {
 	return nil, fmt.Errorf("Element '%s' does not provide interface '%s'", strings.Join(elemPos, "."), strings.Join(pos, "."))
 }
 
+// resolveDependencies links all dependencies and replacements of the element and its
+// children to the referenced interfaces
 func (e *element) resolveDependencies(root *element) []error {
+	aliases, errs := root.aliases()
+	return append(errs, e.resolveReferences(root, aliases)...)
+}
+
+func (e *element) resolveReferences(root *element, aliases map[string]*element) []error {
 	errs := []error{}
 	for _, dep := range e.dependencies {
-		pos := dep.positionFromReference()
-		i, err := root.findInterfaceByPosition(pos)
+		i, target, err := root.findByReference(dep.reference, e, aliases)
 		if err != nil {
 			err = fmt.Errorf("Could not resolve dependency '%s' of element '%s': %w", dep.reference, strings.Join(e.position(), "."), err)
 			errs = append(errs, err)
 		}
 		dep.dependsOn = i
+		dep.dependsOnElement = target
+	}
+	for _, i := range e.interfaces {
+		if i.replacement == "" {
+			continue
+		}
+		r, err := root.findInterfaceByReference(i.replacement, e, aliases)
+		if err != nil {
+			err = fmt.Errorf("Could not resolve replacement '%s' of interface '%s': %w", i.replacement, i.getID("."), err)
+			errs = append(errs, err)
+		}
+		i.replacedBy = r
 	}
 	for _, child := range e.children {
-		childErrs := child.resolveDependencies(root)
+		childErrs := child.resolveReferences(root, aliases)
 		errs = append(errs, childErrs...)
 	}
 	return errs
 }
 
+func (root *element) findInterfaceByReference(ref string, from *element, aliases map[string]*element) (*interf, error) {
+	pos, err := referencePosition(ref, from, aliases)
+	if err != nil {
+		return nil, err
+	}
+	return root.findInterfaceByPosition(pos)
+}
+
+// findByReference returns the interface a reference points to. If the reference points to
+// an element instead, its default interface is returned or, if it has none, the element.
+func (root *element) findByReference(ref string, from *element, aliases map[string]*element) (*interf, *element, error) {
+	pos, err := referencePosition(ref, from, aliases)
+	if err != nil {
+		return nil, nil, err
+	}
+	i, err := root.findInterfaceByPosition(pos)
+	if err == nil {
+		return i, nil, nil
+	}
+	e, elemErr := root.findElementByPosition(pos)
+	if elemErr != nil || e.parent == nil {
+		return nil, nil, err
+	}
+	for _, i := range e.interfaces {
+		if i.fragment == e.defaultIntf {
+			return i, nil, nil
+		}
+	}
+	return nil, e, nil
+}
+
+func (e *element) getElements() []*element {
+	out := []*element{e}
+	for _, elem := range e.children {
+		out = append(out, elem.getElements()...)
+	}
+	return out
+}
+
+func (e *element) getInterfaces() []*interf {
+	out := []*interf{}
+	out = append(out, e.interfaces...)
+	for _, elem := range e.children {
+		out = append(out, elem.getInterfaces()...)
+	}
+	return out
+}
+
 func (e *element) getDependencies() []*dependency {
 	out := e.dependencies
 	for _, elem := range e.children {
@@ -339,9 +749,13 @@ func (e *element) getPropagations() []*interf {
 func (e *element) propagateInterfaces() error {
 	for _, dep := range e.dependencies {
 		i := dep.dependsOn
-		if i == nil {
+		if i == nil && dep.dependsOnElement == nil {
 			return fmt.Errorf("Dependencies of '%s' are not yet resolved", e.getID("."))
 		}
+		// dependencies on whole elements point to the element boundary
+		if i == nil {
+			continue
+		}
 		sibling := e.closestSibling(i.belongsTo)
 		if sibling == nil {
 			return fmt.Errorf("Could not propagate interface '%s' of '%s', no sibling found", i.name, i.belongsTo.getID("."))
@@ -448,7 +862,7 @@ func (base *element) setKeep(k *element) {
 		}
 	}
 	for _, d := range base.dependencies {
-		if d.dependsOn.belongsTo == k || d.dependsOn.belongsTo.hasParent(k) {
+		if d.provider() == k || d.provider().hasParent(k) {
 			d.keep()
 		}
 	}
@@ -3,6 +3,8 @@ package main
 import (
 	"reflect"
 	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
 )
 
 func TestGetPosition(t *testing.T) {
@@ -49,3 +51,152 @@ func TestPositionFromID(t *testing.T) {
 		}
 	}
 }
+
+func TestNewElementConfigurationFromFile(t *testing.T) {
+	files := map[string]string{
+		"README.md":      "---\nname: Service\ndependencies:\n  DB:\n    depends_on: A.SQL\n---\n# Service\n",
+		"sysdoc.yaml":    "name: Service\ndoc: doc/service.md\ndependencies:\n  DB:\n    depends_on: A.SQL\n",
+		"sysdoc.json":    `{"name": "Service", "dependencies": {"DB": {"depends_on": "A.SQL"}}}`,
+		"sysdoc.toml":    "name = \"Service\"\ndoc = \"doc/service.md\"\n[dependencies.DB]\ndepends_on = \"A.SQL\"\n",
+		"doc/service.md": "# Service\n",
+	}
+	fs := memfs.New()
+	writeTestFiles(t, fs, files)
+
+	tests := []struct {
+		file string
+		doc  string
+	}{
+		{file: "README.md", doc: "# Service\n"},
+		{file: "sysdoc.yaml", doc: "# Service\n"},
+		{file: "sysdoc.json", doc: ""},
+		{file: "sysdoc.toml", doc: "# Service\n"},
+	}
+	for _, tc := range tests {
+		ec, err := newElementConfigurationFromFile(tc.file, schemaConfig{}, fs)
+		if err != nil {
+			t.Errorf("%s: %s", tc.file, err)
+			continue
+		}
+		if ec.Name != "Service" || ec.Dependencies["DB"].DependsOn != "A.SQL" {
+			t.Errorf("%s: unexpected configuration %+v", tc.file, ec)
+		}
+		if string(ec.Doc) != tc.doc {
+			t.Errorf("%s: expected doc '%s', got '%s'", tc.file, tc.doc, ec.Doc)
+		}
+	}
+}
+
+func TestInlineChildren(t *testing.T) {
+	files := map[string]string{
+		"README.md":         "---\nname: Root\nchildren:\n  QUEUE:\n    name: Queue\n    interfaces:\n      AMQP: {}\n    children:\n      DLQ:\n        name: Dead Letters\n---\n",
+		"SVC/README.md":     "---\nname: Service\ndependencies:\n  Q:\n    depends_on: QUEUE.AMQP\n---\n",
+		"SVC/JOB/README.md": "---\nname: Job\n---\n",
+	}
+	sys, fs := loadMemSystem(t, files)
+	ids := []string{}
+	for _, e := range sys.getElements() {
+		ids = append(ids, e.getID("."))
+	}
+	want := []string{"", "QUEUE", "QUEUE.DLQ", "SVC", "SVC.JOB"}
+	if !reflect.DeepEqual(want, ids) {
+		t.Errorf("expected %v, got %v", want, ids)
+	}
+
+	writeTestFiles(t, fs, map[string]string{"QUEUE/README.md": "---\nname: Queue\n---\n"})
+	_, err := newElementFromPersistence(".", []string{"README.md"}, config{}, fs)
+	if err == nil {
+		t.Errorf("expected an error for an element defined inline and as directory")
+	}
+}
+
+func TestGlobsAndIgnore(t *testing.T) {
+	files := map[string]string{
+		"README.md":                "---\nname: Root\n---\n",
+		".sysdocignore":            "# dependencies\nnode_modules/\n/build\n",
+		"SVC/README.md":            "---\nname: Readme\n---\n",
+		"SVC/ARCHITECTURE.md":      "---\nname: Architecture\n---\n",
+		"SVC/build/README.md":      "---\nname: Nested build\n---\n",
+		"SVC/.sysdocignore":        "vendor\n",
+		"SVC/vendor/lib/README.md": "---\nname: Vendored\n---\n",
+		"node_modules/x/README.md": "---\nname: Module\n---\n",
+		"build/README.md":          "---\nname: Build\n---\n",
+		"JOB/cron.sysdoc.md":       "---\nname: Job\n---\n",
+		"AMBIGUOUS/a.sysdoc.md":    "---\nname: A\n---\n",
+		"AMBIGUOUS/b.sysdoc.md":    "---\nname: B\n---\n",
+		"MIXED/A.sysdoc.md":        "---\nname: A\n---\n",
+		"MIXED/B.sysdoc.md":        "---\nname: B\n---\n",
+		"MIXED/README.md":          "---\nname: Mixed\n---\n",
+	}
+	fs := memfs.New()
+	writeTestFiles(t, fs, files)
+	globs := []string{"ARCHITECTURE.md", "README.md", "*.sysdoc.md"}
+
+	_, err := newElementFromPersistence(".", globs, config{}, fs)
+	if err == nil {
+		t.Errorf("expected an error for two definitions matching the same glob")
+	}
+
+	err = fs.Remove("AMBIGUOUS/b.sysdoc.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sys, err := newElementFromPersistence(".", globs, config{}, fs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := map[string]string{}
+	for _, e := range sys.getElements() {
+		names[e.getID(".")] = e.name
+	}
+	want := map[string]string{
+		"":          "Root",
+		"AMBIGUOUS": "A",
+		"JOB":       "Job",
+		"MIXED":     "Mixed",
+		"SVC":       "Architecture",
+		"SVC.build": "Nested build",
+	}
+	if !reflect.DeepEqual(want, names) {
+		t.Errorf("expected %v, got %v", want, names)
+	}
+}
+
+func TestSkipEmptyDirectories(t *testing.T) {
+	files := map[string]string{
+		"README.md":                    "---\nname: Root\n---\n",
+		"services/api/README.md":       "---\nname: API\n---\n",
+		"services/api/src/README.md":   "---\nname: Source\n---\n",
+		"services/api/src/main.go":     "package main\n",
+		"libs/util/internal/README.md": "---\nname: Internal\n---\n",
+		"docs/guide.txt":               "guide\n",
+	}
+	fs := memfs.New()
+	writeTestFiles(t, fs, files)
+	cfg := config{SkipEmptyDirectories: true}
+	sys, err := newElementFromPersistence(".", []string{"README.md"}, cfg, fs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := map[string]string{}
+	for _, e := range sys.getElements() {
+		names[e.getID(".")] = e.name
+	}
+	want := map[string]string{
+		"":         "Root",
+		"api":      "API",
+		"api.src":  "Source",
+		"internal": "Internal",
+	}
+	if !reflect.DeepEqual(want, names) {
+		t.Errorf("expected %v, got %v", want, names)
+	}
+
+	sys, err = newElementFromPersistence(".", []string{"README.md"}, config{}, fs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(sys.getElements()); got != 8 {
+		t.Errorf("expected 8 elements without skipping empty directories, got %d", got)
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/util"
+)
+
+var (
+	embedStart      = regexp.MustCompile(`^\s*<!--\s*sysdoc:diagram\b(.*?)-->\s*$`)
+	embedEnd        = regexp.MustCompile(`^\s*<!--\s*/sysdoc\s*-->\s*$`)
+	embedAttributes = []string{"focus", "query", "owner", "view", "depth", "renderer", "lang", "link"}
+)
+
+// embedDiagrams replaces the content between the markers in the Markdown bodies of all
+// definitions with freshly rendered diagrams and returns the files which changed, including
+// the linked diagrams. If check is set, nothing is written. The system must not be filtered.
+func (r *Renderer) embedDiagrams(sys *element, cfg config, filesys billy.Filesystem, check bool) ([]string, error) {
+	changed := []string{}
+	aliases, errs := sys.aliases()
+	if len(errs) > 0 {
+		return changed, errors.Join(errs...)
+	}
+	for _, e := range sys.getElements() {
+		switch strings.ToLower(filepath.Ext(e.file)) {
+		case "", ".yaml", ".yml", ".json", ".toml":
+			continue
+		}
+		data, err := readFile(e.file, filesys)
+		if err != nil {
+			return changed, err
+		}
+		embedded, links, err := r.embed(sys, e, data, cfg, aliases)
+		if err != nil {
+			return changed, fmt.Errorf("Could not embed diagrams in '%s': %w", e.file, err)
+		}
+		files := map[string][]byte{e.file: embedded}
+		for path, content := range links {
+			// a new directory would be an element after the next load
+			if _, err := filesys.Stat(filepath.Dir(path)); err != nil {
+				return changed, fmt.Errorf("Could not link '%s', the directory '%s' does not exist. Please create it and exclude it in .sysdocignore", path, filepath.Dir(path))
+			}
+			files[path] = content
+		}
+		for _, file := range sortedKeys(files) {
+			current, err := readFile(file, filesys)
+			if err == nil && bytes.Equal(current, files[file]) {
+				continue
+			}
+			changed = append(changed, file)
+			if check {
+				continue
+			}
+			err = util.WriteFile(filesys, file, files[file], 0644)
+			if err != nil {
+				return changed, fmt.Errorf("Could not write '%s': %w", file, err)
+			}
+		}
+	}
+	return changed, nil
+}
+
+// embed returns the definition of the element with the content of all markers in its body
+// replaced as well as the content of the linked diagrams by their path
+func (r *Renderer) embed(sys, e *element, data []byte, cfg config, aliases map[string]*element) ([]byte, map[string][]byte, error) {
+	links := map[string][]byte{}
+	lines := strings.Split(string(data), "\n")
+	body := 0
+	if _, end, err := frontmatterLines(lines); err == nil {
+		body = end + 1
+	}
+	out := append([]string{}, lines[:body]...)
+	fenced := false
+	for n := body; n < len(lines); n++ {
+		out = append(out, lines[n])
+		// markers within code blocks are examples
+		if strings.HasPrefix(strings.TrimSpace(lines[n]), "```") {
+			fenced = !fenced
+		}
+		m := embedStart.FindStringSubmatch(lines[n])
+		if fenced || m == nil {
+			continue
+		}
+		end := n + 1
+		for end < len(lines) && !embedEnd.MatchString(lines[end]) {
+			end++
+		}
+		if end == len(lines) {
+			return nil, nil, fmt.Errorf("Marker in line %d is not terminated by '<!-- /sysdoc -->'", n+1)
+		}
+		content, err := r.renderMarker(sys, e, m[1], cfg, aliases, links)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Marker in line %d: %w", n+1, err)
+		}
+		out = append(out, content...)
+		out = append(out, lines[end])
+		n = end
+	}
+	return []byte(strings.Join(out, "\n")), links, nil
+}
+
+// renderMarker renders the diagram declared by the attributes of a marker and returns the
+// lines to be embedded. Diagrams with a link are rendered to the linked file and added to
+// links, all others are embedded as code block of the templates output.
+func (r *Renderer) renderMarker(sys, e *element, raw string, cfg config, aliases map[string]*element, links map[string][]byte) ([]string, error) {
+	attrs := map[string]string{}
+	for _, field := range strings.Fields(raw) {
+		key, value, _ := strings.Cut(field, "=")
+		if !contains(embedAttributes, key) {
+			return nil, fmt.Errorf("Attribute '%s' is not valid, please choose one of %v", key, embedAttributes)
+		}
+		attrs[key] = strings.Trim(value, `"'`)
+	}
+
+	f := filter{Query: attrs["query"], Owner: attrs["owner"], View: attrs["view"]}
+	if attrs["depth"] != "" {
+		depth, err := strconv.Atoi(attrs["depth"])
+		if err != nil || depth < 0 {
+			return nil, fmt.Errorf("Depth '%s' is not valid, it needs to be a number of 0 or more", attrs["depth"])
+		}
+		f.Depth = depth
+	}
+	if f.View != "" && !contains(views, f.View) {
+		return nil, fmt.Errorf("View '%s' is not valid, please choose one of %v", f.View, views)
+	}
+	if attrs["focus"] != "" {
+		for _, ref := range strings.Split(attrs["focus"], ",") {
+			if ref == "self" {
+				f.Focus = append(f.Focus, e.getID("."))
+				continue
+			}
+			pos, err := referencePosition(ref, e, aliases)
+			if err != nil {
+				return nil, err
+			}
+			f.Focus = append(f.Focus, strings.Join(pos, "."))
+		}
+	}
+
+	name := attrs["renderer"]
+	if name == "" {
+		name = "default"
+	}
+	rc, ok := cfg.Renderer[name]
+	if !ok {
+		return nil, fmt.Errorf("Renderer '%s' is not specified, please choose one of %v", name, sortedKeys(cfg.Renderer))
+	}
+	c := sys.clone()
+	err := c.apply(f, cfg)
+	if err != nil {
+		return nil, err
+	}
+	link := attrs["link"]
+	data, err := r.Do(c, rc, link == "")
+	if err != nil {
+		return nil, err
+	}
+
+	if link == "" {
+		// the postprocessor names the language of the templates' output, e.g. d2
+		lang := attrs["lang"]
+		if lang == "" {
+			lang = rc.Postprocessor.Name
+		}
+		return []string{"```" + lang, strings.TrimRight(string(data), "\n"), "```"}, nil
+	}
+	if filepath.IsAbs(link) {
+		return nil, fmt.Errorf("Link '%s' must be relative to the definition", link)
+	}
+	links[filepath.Join(filepath.Dir(e.file), link)] = data
+	return []string{fmt.Sprintf("![%s](%s)", e.name, filepath.ToSlash(link))}, nil
+}
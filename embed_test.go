@@ -0,0 +1,97 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/util"
+)
+
+func TestEmbedDiagrams(t *testing.T) {
+	files := map[string]string{
+		"README.md": "---\nname: System\n---\n# System\n<!-- sysdoc:diagram depth=1 -->\nstale\n<!-- /sysdoc -->\n",
+		"BE/README.md": "---\nname: Backend\n---\n" +
+			"<!-- sysdoc:diagram focus=self renderer=plain link=diagrams/context.txt -->\n<!-- /sysdoc -->\n" +
+			"```\n<!-- sysdoc:diagram focus=self -->\n```\n",
+		"BE/SVC/README.md": "---\nname: Service\ndependencies:\n  ToDB:\n    depends_on: ..DB\n---\n" +
+			"<!-- sysdoc:diagram focus=self,..DB lang=text -->\n<!-- /sysdoc -->",
+		"BE/DB/README.md":   "---\nname: Database\n---\n",
+		"FE/README.md":      "---\nname: Frontend\n---\n",
+		"BE/.sysdocignore":  "diagrams/\n",
+		"BE/diagrams/.keep": "",
+	}
+	sys, filesys := loadMemSystem(t, files)
+
+	cfg := config{Renderer: map[string]renderConfig{"default": idRenderer(), "plain": idRenderer()}}
+	r := NewRenderer()
+
+	want := []string{"README.md", "BE/README.md", "BE/diagrams/context.txt", "BE/SVC/README.md"}
+	changed, err := r.embedDiagrams(sys, cfg, filesys, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(want, changed) {
+		t.Errorf("expected outdated files %v, got %v", want, changed)
+	}
+	if data, _ := util.ReadFile(filesys, "README.md"); string(data) != files["README.md"] {
+		t.Errorf("expected no changes in check mode, got:\n%s", data)
+	}
+
+	changed, err = r.embedDiagrams(sys, cfg, filesys, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(want, changed) {
+		t.Errorf("expected changed files %v, got %v", want, changed)
+	}
+	expected := map[string]string{
+		"README.md": "---\nname: System\n---\n# System\n<!-- sysdoc:diagram depth=1 -->\n```\n;BE;FE;\n```\n<!-- /sysdoc -->\n",
+		"BE/README.md": "---\nname: Backend\n---\n" +
+			"<!-- sysdoc:diagram focus=self renderer=plain link=diagrams/context.txt -->\n![Backend](diagrams/context.txt)\n<!-- /sysdoc -->\n" +
+			"```\n<!-- sysdoc:diagram focus=self -->\n```\n",
+		"BE/SVC/README.md": "---\nname: Service\ndependencies:\n  ToDB:\n    depends_on: ..DB\n---\n" +
+			"<!-- sysdoc:diagram focus=self,..DB lang=text -->\n```text\n;BE;BE.DB;BE.SVC;\n```\n<!-- /sysdoc -->",
+		"BE/diagrams/context.txt": ";BE;BE.DB;BE.SVC;",
+	}
+	for file, content := range expected {
+		data, err := util.ReadFile(filesys, file)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if string(data) != content {
+			t.Errorf("%s: expected:\n%s\ngot:\n%s", file, content, data)
+		}
+	}
+
+	// the linked diagrams must not change the system
+	sys = loadTestSystem(t, filesys, ".", []string{"README.md"}, config{})
+	changed, err = r.embedDiagrams(sys, cfg, filesys, true)
+	if err != nil || len(changed) > 0 {
+		t.Errorf("expected embedded diagrams to be up to date, got %v, %v", changed, err)
+	}
+
+	writeTestFiles(t, filesys, map[string]string{
+		"FE/README.md": "---\nname: Frontend\n---\n<!-- sysdoc:diagram link=new/context.txt -->\n<!-- /sysdoc -->\n",
+	})
+	sys = loadTestSystem(t, filesys, ".", []string{"README.md"}, config{})
+	if _, err := r.embedDiagrams(sys, cfg, filesys, true); err == nil {
+		t.Errorf("expected an error for a link to a new directory")
+	}
+
+	d2 := idRenderer()
+	d2.Postprocessor.Name = "d2"
+	cfg.Renderer["d2"] = d2
+	lines, err := r.renderMarker(sys, sys, "renderer=d2 depth=1", cfg, map[string]*element{}, map[string][]byte{})
+	if err != nil || lines[0] != "```d2" {
+		t.Errorf("expected a code block in the language of the postprocessor, got %v, %v", lines, err)
+	}
+
+	for _, marker := range []string{"<!-- sysdoc:diagram color=red -->\n<!-- /sysdoc -->", "<!-- sysdoc:diagram -->\n"} {
+		_, _, err := r.embed(sys, sys, []byte(marker), cfg, map[string]*element{})
+		if err == nil || !strings.Contains(err.Error(), "Marker in line 1") {
+			t.Errorf("expected an error for marker '%s', got %v", marker, err)
+		}
+	}
+}
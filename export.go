@@ -0,0 +1,215 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+var exportFormats = []string{"csv", "xlsx", "graphml", "cytoscape", "structurizr"}
+
+// table is a sheet of an export sorted by the ID in the first column, tags are flattened
+// into one column per tag
+type table struct {
+	name   string
+	header []string
+	rows   [][]string
+}
+
+// exportTables returns the tables of all elements, interfaces and dependencies
+func exportTables(root *element, now time.Time) []table {
+	elements := table{name: "elements", header: []string{"id", "parent", "fragment", "name", "type", "alias", "team", "contact", "oncall", "file"}}
+	elemTags := []map[string]string{}
+	for _, e := range root.getElements() {
+		parent := ""
+		if e.parent != nil {
+			parent = e.parent.getID(".")
+		}
+		o := e.effectiveOwner()
+		file, _ := e.source()
+		elements.rows = append(elements.rows, []string{e.getID("."), parent, e.fragment, e.name, e.elementType, e.alias, o.Team, o.Contact, o.OnCall, file})
+		elemTags = append(elemTags, e.tags)
+	}
+	elements.addTags(elemTags)
+
+	interfaces := table{name: "interfaces", header: []string{"id", "element", "fragment", "name", "description", "protocol", "version", "status", "deprecated_at", "retired_at", "replaced_by"}}
+	intfTags := []map[string]string{}
+	for _, i := range root.getInterfaces() {
+		replacedBy := ""
+		if i.replacedBy != nil {
+			replacedBy = i.replacedBy.getID(".")
+		}
+		interfaces.rows = append(interfaces.rows, []string{i.getID("."), i.belongsTo.getID("."), i.fragment, i.name, i.description, i.protocol, i.version, i.lifecycleStatus(now), i.deprecatedAt, i.retiredAt, replacedBy})
+		intfTags = append(intfTags, i.tags)
+	}
+	interfaces.addTags(intfTags)
+
+	dependencies := table{name: "dependencies", header: []string{"id", "element", "fragment", "depends_on", "target", "target_element", "description", "kind", "criticality", "protocol", "version"}}
+	depTags := []map[string]string{}
+	for _, d := range root.getDependencies() {
+		target, targetElement := "", ""
+		if d.provider() != nil {
+			target, targetElement = d.targetID("."), d.provider().getID(".")
+		}
+		id := strings.TrimPrefix(d.belongsTo.getID(".")+"."+d.fragment, ".")
+		dependencies.rows = append(dependencies.rows, []string{id, d.belongsTo.getID("."), d.fragment, d.reference, target, targetElement, d.description, d.kind, d.criticality, d.protocol, d.version})
+		depTags = append(depTags, d.tags)
+	}
+	dependencies.addTags(depTags)
+
+	tables := []table{elements, interfaces, dependencies}
+	for _, t := range tables {
+		sort.SliceStable(t.rows, func(a, b int) bool { return t.rows[a][0] < t.rows[b][0] })
+	}
+	return tables
+}
+
+// addTags appends a column 'tag:<key>' for every tag used by any row
+func (t *table) addTags(tags []map[string]string) {
+	keys := map[string]bool{}
+	for _, m := range tags {
+		for k := range m {
+			keys[k] = true
+		}
+	}
+	for _, k := range sortedKeys(keys) {
+		t.header = append(t.header, "tag:"+k)
+		for n := range t.rows {
+			t.rows[n] = append(t.rows[n], tags[n][k])
+		}
+	}
+}
+
+// writeExport writes the tables as one CSV file per table to the directory out or as one
+// sheet per table to the XLSX file out
+func writeExport(tables []table, format, out string) error {
+	switch format {
+	case "csv":
+		err := os.MkdirAll(out, 0755)
+		if err != nil {
+			return err
+		}
+		for _, t := range tables {
+			f, err := os.Create(filepath.Join(out, t.name+".csv"))
+			if err != nil {
+				return err
+			}
+			err = t.writeCSV(f)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				return fmt.Errorf("Could not write '%s': %w", f.Name(), err)
+			}
+		}
+		return nil
+	case "xlsx":
+		f, err := os.Create(out)
+		if err != nil {
+			return err
+		}
+		err = writeXLSX(f, tables)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return fmt.Errorf("Could not write '%s': %w", out, err)
+		}
+		return nil
+	}
+	return fmt.Errorf("Export format '%s' is not supported, please choose one of %v", format, exportFormats)
+}
+
+func (t table) writeCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	err := cw.Write(t.header)
+	if err != nil {
+		return err
+	}
+	err = cw.WriteAll(t.rows)
+	if err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+// writeXLSX writes a minimal Office Open XML workbook with one sheet per table, all cells
+// are written as inline strings
+func writeXLSX(w io.Writer, tables []table) error {
+	var sheets, sheetRels, sheetTypes strings.Builder
+	for n, t := range tables {
+		fmt.Fprintf(&sheets, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, xmlEscape(t.name), n+1, n+1)
+		fmt.Fprintf(&sheetRels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, n+1, n+1)
+		fmt.Fprintf(&sheetTypes, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, n+1)
+	}
+	files := []struct{ name, content string }{
+		{"[Content_Types].xml", xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+			`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+			`<Default Extension="xml" ContentType="application/xml"/>` +
+			`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+			sheetTypes.String() + `</Types>`},
+		{"_rels/.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+			`</Relationships>`},
+		{"xl/workbook.xml", xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets>` + sheets.String() + `</sheets></workbook>`},
+		{"xl/_rels/workbook.xml.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			sheetRels.String() + `</Relationships>`},
+	}
+	for n, t := range tables {
+		files = append(files, struct{ name, content string }{fmt.Sprintf("xl/worksheets/sheet%d.xml", n+1), t.sheetXML()})
+	}
+
+	zw := zip.NewWriter(w)
+	for _, f := range files {
+		fw, err := zw.Create(f.name)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(fw, f.content)
+		if err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func (t table) sheetXML() string {
+	var b strings.Builder
+	b.WriteString(xml.Header + `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	for n, row := range append([][]string{t.header}, t.rows...) {
+		fmt.Fprintf(&b, `<row r="%d">`, n+1)
+		for c, value := range row {
+			if value == "" {
+				continue
+			}
+			fmt.Fprintf(&b, `<c r="%s%d" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, columnName(c), n+1, xmlEscape(value))
+		}
+		b.WriteString(`</row>`)
+	}
+	b.WriteString(`</sheetData></worksheet>`)
+	return b.String()
+}
+
+// columnName returns the spreadsheet name of the column with the zero based index, e.g. 'AA'
+// for 26
+func columnName(index int) string {
+	name := ""
+	for index++; index > 0; index = (index - 1) / 26 {
+		name = string(rune('A'+(index-1)%26)) + name
+	}
+	return name
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
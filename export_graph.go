@@ -0,0 +1,268 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+var graphFormats = []string{"graphml", "cytoscape"}
+
+// graphNode is an element or an interface, nested in the node of its parent element
+type graphNode struct {
+	id     string
+	parent string
+	attrs  map[string]string
+}
+
+// graphEdge is a dependency from an element to an interface or a whole element
+type graphEdge struct {
+	id     string
+	source string
+	target string
+	attrs  map[string]string
+}
+
+type graph struct {
+	nodes []graphNode
+	edges []graphEdge
+}
+
+// newGraph returns the resolved dependency graph below the root element, which is not part
+// of the graph itself. Its interfaces are top level nodes, edges from or to the root element
+// are dropped. Tags become attributes named 'tag:<key>'. The IDs of interfaces are prefixed
+// with 'if:' and those of edges with 'dep:' to keep them apart from the IDs of elements.
+func newGraph(root *element, now time.Time) graph {
+	g := graph{nodes: []graphNode{}, edges: []graphEdge{}}
+	edges := []graphEdge{}
+	for _, e := range root.getElements() {
+		if e.parent != nil {
+			o := e.effectiveOwner()
+			g.nodes = append(g.nodes, graphNode{
+				id:     e.getID("."),
+				parent: e.parent.getID("."),
+				attrs:  graphAttributes(e.tags, "kind", "element", "name", e.name, "type", e.elementType, "team", o.Team),
+			})
+		}
+		for _, i := range e.interfaces {
+			g.nodes = append(g.nodes, graphNode{
+				id:     graphInterfaceID(i),
+				parent: e.getID("."),
+				attrs: graphAttributes(i.tags, "kind", "interface", "name", i.name, "description", i.description,
+					"protocol", i.protocol, "version", i.version, "status", i.lifecycleStatus(now)),
+			})
+		}
+		for _, d := range e.dependencies {
+			if d.provider() == nil {
+				continue
+			}
+			target := d.targetID(".")
+			if d.dependsOn != nil {
+				target = graphInterfaceID(d.dependsOn)
+			}
+			edges = append(edges, graphEdge{
+				id:     "dep:" + strings.TrimPrefix(e.getID(".")+"."+d.fragment, "."),
+				source: e.getID("."),
+				target: target,
+				attrs: graphAttributes(d.tags, "description", d.description, "kind", d.kind, "criticality", d.criticality,
+					"protocol", d.protocol, "version", d.version),
+			})
+		}
+	}
+	nodes := map[string]bool{}
+	for _, n := range g.nodes {
+		nodes[n.id] = true
+	}
+	for _, edge := range edges {
+		if nodes[edge.source] && nodes[edge.target] {
+			g.edges = append(g.edges, edge)
+		}
+	}
+	sort.SliceStable(g.nodes, func(a, b int) bool { return g.nodes[a].id < g.nodes[b].id })
+	sort.SliceStable(g.edges, func(a, b int) bool { return g.edges[a].id < g.edges[b].id })
+	return g
+}
+
+func graphInterfaceID(i *interf) string {
+	return "if:" + strings.TrimPrefix(i.getID("."), ".")
+}
+
+// graphAttributes returns the non empty attributes of the given pairs along with the tags
+func graphAttributes(tags map[string]string, pairs ...string) map[string]string {
+	out := map[string]string{}
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if pairs[i+1] != "" {
+			out[pairs[i]] = pairs[i+1]
+		}
+	}
+	for k, v := range tags {
+		out["tag:"+k] = v
+	}
+	return out
+}
+
+// writeGraphExport writes the graph to the file out in the given format
+func writeGraphExport(g graph, format, out string) error {
+	f, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	err = g.Write(f, format)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("Could not write '%s': %w", out, err)
+	}
+	return nil
+}
+
+// Write prints the graph as GraphML with nested graphs for the children of a node or as
+// Cytoscape.js JSON with compound nodes
+func (g graph) Write(w io.Writer, format string) error {
+	switch format {
+	case "graphml":
+		return g.writeGraphML(w)
+	case "cytoscape":
+		type item struct {
+			Data map[string]string `json:"data"`
+		}
+		doc := struct {
+			Elements struct {
+				Nodes []item `json:"nodes"`
+				Edges []item `json:"edges"`
+			} `json:"elements"`
+		}{}
+		doc.Elements.Nodes, doc.Elements.Edges = []item{}, []item{}
+		for _, n := range g.nodes {
+			data := map[string]string{"id": n.id}
+			if n.parent != "" {
+				data["parent"] = n.parent
+			}
+			for k, v := range n.attrs {
+				data[k] = v
+			}
+			doc.Elements.Nodes = append(doc.Elements.Nodes, item{data})
+		}
+		for _, e := range g.edges {
+			data := map[string]string{"id": e.id, "source": e.source, "target": e.target}
+			for k, v := range e.attrs {
+				data[k] = v
+			}
+			doc.Elements.Edges = append(doc.Elements.Edges, item{data})
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(doc)
+	}
+	return fmt.Errorf("Graph format '%s' is not supported, please choose one of %v", format, graphFormats)
+}
+
+type graphmlKey struct {
+	ID   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr"`
+	Type string `xml:"attr.type,attr"`
+}
+
+type graphmlData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+type graphmlNode struct {
+	ID    string        `xml:"id,attr"`
+	Data  []graphmlData `xml:"data"`
+	Graph *graphmlGraph `xml:"graph,omitempty"`
+}
+
+type graphmlEdge struct {
+	ID     string        `xml:"id,attr"`
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphmlData `xml:"data"`
+}
+
+type graphmlGraph struct {
+	ID          string         `xml:"id,attr"`
+	EdgeDefault string         `xml:"edgedefault,attr"`
+	Nodes       []*graphmlNode `xml:"node"`
+	Edges       []graphmlEdge  `xml:"edge"`
+}
+
+func (g graph) writeGraphML(w io.Writer) error {
+	doc := struct {
+		XMLName xml.Name      `xml:"http://graphml.graphdrawing.org/xmlns graphml"`
+		Keys    []graphmlKey  `xml:"key"`
+		Graph   *graphmlGraph `xml:"graph"`
+	}{Graph: &graphmlGraph{ID: "G", EdgeDefault: "directed"}}
+
+	keys := map[string]string{}
+	addKeys := func(kind, prefix string, attrs []map[string]string) {
+		names := map[string]bool{}
+		for _, a := range attrs {
+			for k := range a {
+				names[k] = true
+			}
+		}
+		for n, name := range sortedKeys(names) {
+			id := fmt.Sprintf("%s%d", prefix, n)
+			keys[kind+"/"+name] = id
+			doc.Keys = append(doc.Keys, graphmlKey{ID: id, For: kind, Name: name, Type: "string"})
+		}
+	}
+	data := func(kind string, attrs map[string]string) []graphmlData {
+		out := []graphmlData{}
+		for _, k := range sortedKeys(attrs) {
+			out = append(out, graphmlData{Key: keys[kind+"/"+k], Value: attrs[k]})
+		}
+		return out
+	}
+	nodeAttrs, edgeAttrs := []map[string]string{}, []map[string]string{}
+	for _, n := range g.nodes {
+		nodeAttrs = append(nodeAttrs, n.attrs)
+	}
+	for _, e := range g.edges {
+		edgeAttrs = append(edgeAttrs, e.attrs)
+	}
+	addKeys("node", "n", nodeAttrs)
+	addKeys("edge", "e", edgeAttrs)
+
+	nodes := map[string]*graphmlNode{}
+	for _, n := range g.nodes {
+		nodes[n.id] = &graphmlNode{ID: n.id, Data: data("node", n.attrs)}
+	}
+	for _, n := range g.nodes {
+		node := nodes[n.id]
+		parent, ok := nodes[n.parent]
+		if !ok {
+			doc.Graph.Nodes = append(doc.Graph.Nodes, node)
+			continue
+		}
+		if parent.Graph == nil {
+			parent.Graph = &graphmlGraph{ID: parent.ID + ":", EdgeDefault: "directed"}
+		}
+		parent.Graph.Nodes = append(parent.Graph.Nodes, node)
+	}
+	for _, e := range g.edges {
+		doc.Graph.Edges = append(doc.Graph.Edges, graphmlEdge{ID: e.id, Source: e.source, Target: e.target, Data: data("edge", e.attrs)})
+	}
+
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	err = enc.Encode(doc)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGraphExport(t *testing.T) {
+	root := newElement("root", elementConfiguration{
+		Interfaces:   map[string]interfConfiguration{"Pub": {}},
+		Dependencies: map[string]dependencyConfiguration{"ToFE": {DependsOn: "FE"}},
+		Children: map[string]elementConfiguration{
+			"BE": {
+				Name: "Backend",
+				Tags: map[string]string{"tier": "1"},
+				Children: map[string]elementConfiguration{
+					"DB": {Interfaces: map[string]interfConfiguration{"SQL": {Protocol: "postgres"}}},
+				},
+			},
+			"FE": {
+				Interfaces: map[string]interfConfiguration{"API": {}},
+				Children:   map[string]elementConfiguration{"API": {}},
+				Dependencies: map[string]dependencyConfiguration{
+					"API":  {DependsOn: "Pub"},
+					"Data": {DependsOn: "BE.DB.SQL", Criticality: "soft"},
+					"All":  {DependsOn: "BE"},
+				},
+			},
+		},
+	})
+	if errs := root.resolveDependencies(root); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	g := newGraph(root, time.Now())
+
+	var b bytes.Buffer
+	if err := g.Write(&b, "cytoscape"); err != nil {
+		t.Fatal(err)
+	}
+	doc := struct {
+		Elements struct {
+			Nodes []struct{ Data map[string]string }
+			Edges []struct{ Data map[string]string }
+		}
+	}{}
+	if err := json.Unmarshal(b.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	nodes := []string{}
+	for _, n := range doc.Elements.Nodes {
+		nodes = append(nodes, n.Data["id"]+"<"+n.Data["parent"])
+	}
+	if want := "BE< BE.DB<BE FE< FE.API<FE if:BE.DB.SQL<BE.DB if:FE.API<FE if:Pub<"; strings.Join(nodes, " ") != want {
+		t.Errorf("expected nodes '%s', got '%s'", want, strings.Join(nodes, " "))
+	}
+	if got := doc.Elements.Nodes[0].Data["tag:tier"]; got != "1" {
+		t.Errorf("expected tag attribute 'tag:tier' to be '1', got '%s'", got)
+	}
+	edges := []string{}
+	for _, e := range doc.Elements.Edges {
+		edges = append(edges, e.Data["source"]+">"+e.Data["target"]+":"+e.Data["criticality"])
+	}
+	if want := "FE>if:Pub:hard FE>BE:hard FE>if:BE.DB.SQL:soft"; strings.Join(edges, " ") != want {
+		t.Errorf("expected edges '%s', got '%s'", want, strings.Join(edges, " "))
+	}
+
+	// nodes and edges share the IDs in Cytoscape.js
+	ids := map[string]bool{}
+	for _, item := range append(doc.Elements.Nodes, doc.Elements.Edges...) {
+		if ids[item.Data["id"]] {
+			t.Errorf("expected unique IDs, got '%s' twice", item.Data["id"])
+		}
+		ids[item.Data["id"]] = true
+	}
+
+	b.Reset()
+	if err := g.Write(&b, "graphml"); err != nil {
+		t.Fatal(err)
+	}
+	if err := xml.Unmarshal(b.Bytes(), new(interface{})); err != nil {
+		t.Errorf("expected valid xml, got %v", err)
+	}
+	for _, want := range []string{
+		`<key id="n4" for="node" attr.name="tag:tier" attr.type="string"></key>`,
+		"<node id=\"BE\">\n      <data key=\"n0\">element</data>\n      <data key=\"n1\">Backend</data>\n      <data key=\"n4\">1</data>\n      <graph id=\"BE:\" edgedefault=\"directed\">\n        <node id=\"BE.DB\">",
+		`<edge id="dep:FE.Data" source="FE" target="if:BE.DB.SQL">`,
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("expected graphml to contain:\n%s\ngot:\n%s", want, b.String())
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"archive/zip"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportTables(t *testing.T) {
+	root := newElement("root", elementConfiguration{
+		Children: map[string]elementConfiguration{
+			"BE": {
+				Name:  "Backend",
+				Owner: owner{Team: "backend"},
+				Tags:  map[string]string{"tier": "1"},
+				Children: map[string]elementConfiguration{
+					"DB": {Interfaces: map[string]interfConfiguration{
+						"SQL": {Protocol: "postgres", ReplacedBy: ".V2"},
+						"V2":  {Tags: map[string]string{"link": "https://example.com"}},
+					}},
+				},
+			},
+			"FE": {
+				Dependencies: map[string]dependencyConfiguration{
+					"Data": {DependsOn: "BE.DB.SQL", Kind: "sync"},
+					"All":  {DependsOn: "BE"},
+				},
+			},
+		},
+	})
+	if errs := root.resolveDependencies(root); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	tables := exportTables(root, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	if len(tables) != 3 {
+		t.Fatalf("expected 3 tables, got %d", len(tables))
+	}
+	elements, interfaces, dependencies := tables[0], tables[1], tables[2]
+
+	if want := "tag:tier"; elements.header[len(elements.header)-1] != want {
+		t.Errorf("expected last element column '%s', got %v", want, elements.header)
+	}
+	rows := map[string][]string{}
+	for _, row := range elements.rows {
+		rows[row[0]] = row
+	}
+	if want := []string{"BE.DB", "BE", "DB", "", "", "", "backend", "", "", "", ""}; !reflect.DeepEqual(want, rows["BE.DB"]) {
+		t.Errorf("expected element row %v, got %v", want, rows["BE.DB"])
+	}
+
+	if want := []string{"BE.DB.SQL", "BE.DB", "SQL", "", "", "postgres", "", "active", "", "", "BE.DB.V2", ""}; !reflect.DeepEqual(want, interfaces.rows[0]) {
+		t.Errorf("expected interface row %v, got %v", want, interfaces.rows[0])
+	}
+
+	targets := map[string][]string{}
+	for _, row := range dependencies.rows {
+		targets[row[0]] = row[3:6]
+	}
+	want := map[string][]string{
+		"FE.Data": {"BE.DB.SQL", "BE.DB.SQL", "BE.DB"},
+		"FE.All":  {"BE", "BE", "BE"},
+	}
+	if !reflect.DeepEqual(want, targets) {
+		t.Errorf("expected dependency targets %v, got %v", want, targets)
+	}
+
+	dir := t.TempDir()
+	if err := writeExport(tables, "csv", filepath.Join(dir, "csv")); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "csv", "interfaces.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(data), "id,element,fragment,name,description,protocol,version,status,deprecated_at,retired_at,replaced_by,tag:link\n") {
+		t.Errorf("unexpected csv:\n%s", data)
+	}
+
+	if err := writeExport(tables, "xlsx", filepath.Join(dir, "export.xlsx")); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.OpenReader(filepath.Join(dir, "export.xlsx"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	contents := map[string]string{}
+	for _, f := range zr.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := io.ReadAll(r)
+		r.Close()
+		contents[f.Name] = string(b)
+	}
+	if !strings.Contains(contents["xl/workbook.xml"], `<sheet name="dependencies" sheetId="3" r:id="rId3"/>`) {
+		t.Errorf("expected a sheet per table, got:\n%s", contents["xl/workbook.xml"])
+	}
+	if !strings.Contains(contents["xl/worksheets/sheet3.xml"], `<c r="E3" t="inlineStr"><is><t xml:space="preserve">BE.DB.SQL</t></is></c>`) {
+		t.Errorf("expected the target in the dependencies sheet, got:\n%s", contents["xl/worksheets/sheet3.xml"])
+	}
+
+	if err := writeExport(tables, "pdf", dir); err == nil {
+		t.Error("expected an error for an unsupported format")
+	}
+}
+
+func TestColumnName(t *testing.T) {
+	for index, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 51: "AZ", 52: "BA", 701: "ZZ", 702: "AAA"} {
+		if got := columnName(index); got != want {
+			t.Errorf("expected column %d to be '%s', got '%s'", index, want, got)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/util"
+	"gopkg.in/yaml.v3"
+)
+
+// formatDefinitions rewrites the YAML of all definition files below root canonically and
+// returns the files which changed. If check is set, the files are not written. JSON and
+// TOML files are left as they are.
+func (root *element) formatDefinitions(filesys billy.Filesystem, check bool) ([]string, error) {
+	changed := []string{}
+	files := map[string]bool{}
+	for _, e := range root.getElements() {
+		if e.file != "" {
+			files[e.file] = true
+		}
+	}
+	for _, file := range sortedKeys(files) {
+		switch strings.ToLower(filepath.Ext(file)) {
+		case ".json", ".toml":
+			continue
+		}
+		data, err := readFile(file, filesys)
+		if err != nil {
+			return changed, err
+		}
+		formatted, err := formatDefinition(file, data)
+		if err != nil {
+			return changed, fmt.Errorf("Could not format '%s': %w", file, err)
+		}
+		if bytes.Equal(data, formatted) {
+			continue
+		}
+		changed = append(changed, file)
+		if check {
+			continue
+		}
+		info, err := filesys.Stat(file)
+		if err != nil {
+			return changed, err
+		}
+		err = util.WriteFile(filesys, file, formatted, info.Mode())
+		if err != nil {
+			return changed, fmt.Errorf("Could not write '%s': %w", file, err)
+		}
+	}
+	return changed, nil
+}
+
+// formatDefinition returns the definition with its YAML formatted canonically, the Markdown
+// body stays untouched
+func formatDefinition(file string, data []byte) ([]byte, error) {
+	lines := strings.Split(string(data), "\n")
+	start, end := 0, len(lines)
+	plain := true
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml":
+	default:
+		var err error
+		start, end, err = frontmatterLines(lines)
+		if err != nil {
+			return nil, err
+		}
+		plain = false
+	}
+	doc := yaml.Node{}
+	err := yaml.Unmarshal([]byte(strings.Join(lines[start:end], "\n")+"\n"), &doc)
+	if err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return data, nil
+	}
+	canonical(doc.Content[0], reflect.TypeOf(elementConfiguration{}), !plain)
+	encoded, err := encodeYAML(&doc)
+	if err != nil {
+		return nil, err
+	}
+	if plain {
+		return []byte(encoded), nil
+	}
+	out := append([]string{lines[0], strings.TrimSuffix(encoded, "\n")}, lines[end:]...)
+	return []byte(strings.Join(out, "\n")), nil
+}
+
+// canonical orders the keys of mappings decoded into structs by the order of the struct
+// fields and all other mappings alphabetically. Quoting and flow styles are reset so that
+// the encoder picks them consistently, yaml11 is set for front matter.
+func canonical(n *yaml.Node, t reflect.Type, yaml11 bool) {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch n.Kind {
+	case yaml.MappingNode:
+		n.Style &^= yaml.FlowStyle
+		order := map[string]int{}
+		fields := map[string]reflect.Type{}
+		if t != nil && t.Kind() == reflect.Struct {
+			for i := 0; i < t.NumField(); i++ {
+				key, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+				if key == "" || key == "-" {
+					continue
+				}
+				order[key] = i
+				fields[key] = t.Field(i).Type
+			}
+		}
+		type pair struct{ key, value *yaml.Node }
+		pairs := []pair{}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			key, value := n.Content[i], n.Content[i+1]
+			var kt, vt reflect.Type
+			if t != nil && t.Kind() == reflect.Map {
+				kt, vt = t.Key(), t.Elem()
+			} else if t != nil && t.Kind() == reflect.Struct {
+				kt, vt = reflect.TypeOf(""), fields[key.Value]
+			}
+			canonical(key, kt, yaml11)
+			canonical(value, vt, yaml11)
+			pairs = append(pairs, pair{key, value})
+		}
+		sort.SliceStable(pairs, func(a, b int) bool {
+			oa, knownA := order[pairs[a].key.Value]
+			ob, knownB := order[pairs[b].key.Value]
+			if knownA != knownB {
+				return knownA
+			}
+			if knownA {
+				return oa < ob
+			}
+			return pairs[a].key.Value < pairs[b].key.Value
+		})
+		n.Content = n.Content[:0]
+		for _, p := range pairs {
+			n.Content = append(n.Content, p.key, p.value)
+		}
+	case yaml.SequenceNode:
+		n.Style &^= yaml.FlowStyle
+		var et reflect.Type
+		if t != nil && t.Kind() == reflect.Slice {
+			et = t.Elem()
+		}
+		for _, c := range n.Content {
+			canonical(c, et, yaml11)
+		}
+	case yaml.ScalarNode:
+		quoted := n.Style&(yaml.SingleQuotedStyle|yaml.DoubleQuotedStyle) != 0
+		n.Style &^= yaml.SingleQuotedStyle | yaml.DoubleQuotedStyle
+		// the values of these strings differ between YAML 1.1 and 1.2, the front matter
+		// is parsed as YAML 1.1 where unquoted they are booleans unless decoded into a string
+		value := strings.ToLower(n.Value)
+		switch {
+		case n.Tag != "!!str" || !contains(yaml11Bools, value):
+		case yaml11 && !quoted && (t == nil || t.Kind() != reflect.String):
+			n.Value, n.Tag = fmt.Sprint(contains([]string{"y", "yes", "on"}, value)), "!!bool"
+		default:
+			n.Style |= yaml.DoubleQuotedStyle
+		}
+	}
+}
+
+var yaml11Bools = []string{"y", "yes", "n", "no", "on", "off"}
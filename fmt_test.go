@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestFormatDefinition(t *testing.T) {
+	tests := []struct {
+		file string
+		in   string
+		want string
+	}{
+		{
+			file: "README.md",
+			in:   "---\ntags: {z: \"1\", a: 'x', b: \"yes\", c: on}\nfields: {on: off}\n# the name\nname:   \"Backend\"\ninterfaces:\n    B: {}\n    A:\n        version: \"1.0\"\n        protocol: https\ndoc: |\n  multi\n  line\ntype: software_system\n---\n# Body\n\n  kept   as is  \n",
+			want: "---\n# the name\nname: Backend\ntype: software_system\ntags:\n  a: x\n  b: \"yes\"\n  c: \"on\"\n  z: \"1\"\nfields:\n  \"on\": false\ninterfaces:\n  A:\n    protocol: https\n    version: \"1.0\"\n  B: {}\ndoc: |\n  multi\n  line\n---\n# Body\n\n  kept   as is  \n",
+		},
+		{
+			file: "sysdoc.yaml",
+			in:   "children:\n  b: {name: B}\n  a:\n    dependencies:\n      X: {kind: async, depends_on: A.IF}\nname: 'on'\n",
+			want: "name: \"on\"\nchildren:\n  a:\n    dependencies:\n      X:\n        depends_on: A.IF\n        kind: async\n  b:\n    name: B\n",
+		},
+		{
+			file: "README.md",
+			in:   "---\n---\nOnly a body\n",
+			want: "---\n---\nOnly a body\n",
+		},
+	}
+	for _, tc := range tests {
+		got, err := formatDefinition(tc.file, []byte(tc.in))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != tc.want {
+			t.Errorf("%s: expected:\n%s\ngot:\n%s", tc.file, tc.want, got)
+		}
+		again, err := formatDefinition(tc.file, got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(again) != string(got) {
+			t.Errorf("%s: formatting is not idempotent:\n%s", tc.file, again)
+		}
+	}
+}
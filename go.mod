@@ -3,6 +3,8 @@ module sysdoc
 go 1.20
 
 require (
+	cdr.dev/slog v1.4.2
+	github.com/BurntSushi/toml v1.3.2
 	github.com/adrg/frontmatter v0.2.0
 	github.com/carlmjohnson/versioninfo v0.22.5
 	github.com/go-git/go-billy/v5 v5.5.0
@@ -15,9 +17,7 @@ require (
 )
 
 require (
-	cdr.dev/slog v1.4.2 // indirect
 	dario.cat/mergo v1.0.0 // indirect
-	github.com/BurntSushi/toml v1.3.2 // indirect
 	github.com/Microsoft/go-winio v0.6.1 // indirect
 	github.com/ProtonMail/go-crypto v1.0.0 // indirect
 	github.com/PuerkitoBio/goquery v1.8.1 // indirect
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+)
+
+// writeTestFiles writes the files by their path to filesys
+func writeTestFiles(t *testing.T, filesys billy.Filesystem, files map[string]string) {
+	t.Helper()
+	for name, data := range files {
+		err := util.WriteFile(filesys, name, []byte(data), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// loadTestSystem loads the system below basedir and resolves its dependencies
+func loadTestSystem(t *testing.T, filesys billy.Filesystem, basedir string, globs []string, cfg config) *element {
+	t.Helper()
+	sys, err := newElementFromPersistence(basedir, globs, cfg, filesys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errs := sys.resolveDependencies(sys); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	return sys
+}
+
+// loadMemSystem writes the files to an in-memory filesystem and loads the system defined by
+// their README.md files
+func loadMemSystem(t *testing.T, files map[string]string) (*element, billy.Filesystem) {
+	t.Helper()
+	filesys := memfs.New()
+	writeTestFiles(t, filesys, files)
+	return loadTestSystem(t, filesys, ".", []string{"README.md"}, config{}), filesys
+}
+
+// idRenderer returns a renderer which prints the IDs of all rendered elements, each
+// followed by ';'
+func idRenderer() renderConfig {
+	rc := renderConfig{}
+	rc.Templates.Element = `{{.ID "."}};{{range .Children}}{{.}}{{end}}`
+	rc.Templates.Global = "{{.Elements}}"
+	return rc
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"sort"
+)
+
+const (
+	impactDegradation = "degradation"
+	impactOutage      = "outage"
+)
+
+type impact struct {
+	ID     string `json:"id"`
+	Impact string `json:"impact"`
+}
+
+// impactOf determines which elements are affected if the given elements fail. Elements
+// with a hard dependency on a failing element fail as well, soft dependencies or
+// dependencies on degraded elements lead to a degradation.
+func (root *element) impactOf(failing []*element) []impact {
+	status := map[*element]string{}
+	for _, f := range failing {
+		for _, e := range f.getElements() {
+			status[e] = impactOutage
+		}
+	}
+
+	deps := root.getDependencies()
+	changed := true
+	for changed {
+		changed = false
+		for _, dep := range deps {
+			if dep.provider() == nil {
+				continue
+			}
+			target, ok := status[dep.provider()]
+			if !ok {
+				continue
+			}
+			next := impactDegradation
+			if target == impactOutage && dep.criticality == "hard" {
+				next = impactOutage
+			}
+			current, ok := status[dep.belongsTo]
+			if !ok || (current == impactDegradation && next == impactOutage) {
+				status[dep.belongsTo] = next
+				changed = true
+			}
+		}
+	}
+
+	out := []impact{}
+	for e, s := range status {
+		out = append(out, impact{ID: e.getID("."), Impact: s})
+	}
+	sort.Slice(out, func(a, b int) bool { return out[a].ID < out[b].ID })
+	return out
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"reflect"
+	"sysdoc/internal/persistence"
+	"testing"
+)
+
+func TestImpactOf(t *testing.T) {
+	p, err := persistence.NewLocal("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sys, errs := NewSystem(".", []string{"README.md"}, filter{}, config{}, p)
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	tests := []struct {
+		failing string
+		want    []impact
+	}{
+		{failing: "FE", want: []impact{{ID: "FE", Impact: impactOutage}, {ID: "FE.WEB", Impact: impactOutage}}},
+		{failing: "BE.DB", want: []impact{{ID: "BE.DB", Impact: impactOutage}, {ID: "BE.SVC", Impact: impactOutage}, {ID: "FE.WEB", Impact: impactDegradation}}},
+	}
+	for _, tc := range tests {
+		e, err := sys.findElementByPosition(positionFromID(tc.failing, "."))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := sys.impactOf([]*element{e})
+		if !reflect.DeepEqual(tc.want, got) {
+			t.Errorf("failing '%s': expected %v, got %v", tc.failing, tc.want, got)
+		}
+	}
+}
+
+func TestDependencyConfigurationValidate(t *testing.T) {
+	tests := []struct {
+		c     dependencyConfiguration
+		valid bool
+	}{
+		{c: dependencyConfiguration{}, valid: true},
+		{c: dependencyConfiguration{Kind: "async", Criticality: "soft"}, valid: true},
+		{c: dependencyConfiguration{Kind: "realtime"}, valid: false},
+		{c: dependencyConfiguration{Criticality: "medium"}, valid: false},
+	}
+	for _, tc := range tests {
+		err := tc.c.validate()
+		if (err == nil) != tc.valid {
+			t.Errorf("%+v: expected valid to be %t, got error %v", tc.c, tc.valid, err)
+		}
+	}
+}
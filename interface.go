@@ -3,33 +3,99 @@ package main
 import (
 	"fmt"
 	"strings"
+	"time"
 )
 
+const dateFormat = "2006-01-02"
+
+const (
+	statusPlanned    = "planned"
+	statusActive     = "active"
+	statusDeprecated = "deprecated"
+	statusRetired    = "retired"
+)
+
+var interfStatuses = []string{statusPlanned, statusActive, statusDeprecated, statusRetired}
+
 type interfConfiguration struct {
-	Name        string            `yaml:"name" json:"name"`
-	Description string            `yaml:"description" json:"description"`
-	Tags        map[string]string `yaml:"tags" json:"tags"`
+	Name         string            `yaml:"name" json:"name"`
+	Description  string            `yaml:"description" json:"description"`
+	Protocol     string            `yaml:"protocol" json:"protocol"`
+	Version      string            `yaml:"version" json:"version"`
+	Status       string            `yaml:"status" json:"status"`
+	DeprecatedAt string            `yaml:"deprecated_at" json:"deprecated_at"`
+	RetiredAt    string            `yaml:"retired_at" json:"retired_at"`
+	ReplacedBy   string            `yaml:"replaced_by" json:"replaced_by"`
+	Tags         map[string]string `yaml:"tags" json:"tags"`
+}
+
+func (c interfConfiguration) validate() error {
+	if c.Status != "" && !contains(interfStatuses, c.Status) {
+		return fmt.Errorf("Status '%s' is not valid, please choose one of %v", c.Status, interfStatuses)
+	}
+	for _, date := range []string{c.DeprecatedAt, c.RetiredAt} {
+		if date == "" {
+			continue
+		}
+		_, err := time.Parse(dateFormat, date)
+		if err != nil {
+			return fmt.Errorf("Date '%s' is not valid, please use the format YYYY-MM-DD", date)
+		}
+	}
+	return nil
 }
 
 type interf struct {
-	fragment    string
-	name        string
-	description string
-	tags        map[string]string
+	fragment     string
+	name         string
+	description  string
+	protocol     string
+	version      string
+	status       string
+	deprecatedAt string
+	retiredAt    string
+	replacement  string
+	declaredTags map[string]string
+	tags         map[string]string
 
 	belongsTo  *element
 	propagates *interf
+	replacedBy *interf
+	metrics    *interfMetrics
 	k          bool
 }
 
 func newInterf(fragment string, c interfConfiguration, e *element) *interf {
 	return &interf{
-		fragment:    fragment,
-		name:        c.Name,
-		description: c.Description,
-		tags:        c.Tags,
-		belongsTo:   e,
+		fragment:     fragment,
+		name:         c.Name,
+		description:  c.Description,
+		protocol:     c.Protocol,
+		version:      c.Version,
+		status:       c.Status,
+		deprecatedAt: c.DeprecatedAt,
+		retiredAt:    c.RetiredAt,
+		replacement:  c.ReplacedBy,
+		declaredTags: c.Tags,
+		tags:         c.Tags,
+		belongsTo:    e,
+	}
+}
+
+// lifecycleStatus returns the configured status of the interface, unless a deprecation
+// or retirement date has already passed
+func (i *interf) lifecycleStatus(now time.Time) string {
+	today := now.Format(dateFormat)
+	if i.retiredAt != "" && i.retiredAt <= today {
+		return statusRetired
+	}
+	if i.deprecatedAt != "" && i.deprecatedAt <= today && i.status != statusRetired {
+		return statusDeprecated
+	}
+	if i.status == "" {
+		return statusActive
 	}
+	return i.status
 }
 
 func (i *interf) propagateTo(e *element) *interf {
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// lint returns warnings about questionable but valid definitions below root. It needs
+// to be called on a tree with resolved dependencies.
+func (root *element) lint(now time.Time) []string {
+	warnings := []string{}
+	for _, dep := range root.getDependencies() {
+		id := fmt.Sprintf("%s.%s", dep.belongsTo.getID("."), dep.fragment)
+		for _, i := range dep.providedInterfaces() {
+			warnings = append(warnings, dep.lint(id, i, now)...)
+		}
+	}
+	for _, i := range root.getInterfaces() {
+		if i.replacedBy != nil && i.replacedBy.lifecycleStatus(now) == statusRetired {
+			warnings = append(warnings, fmt.Sprintf("Interface '%s' is replaced by retired interface '%s'", i.getID("."), i.replacedBy.getID(".")))
+		}
+	}
+	return warnings
+}
+
+// lint returns warnings about the usage of the interface i by the dependency
+func (dep *dependency) lint(id string, i *interf, now time.Time) []string {
+	warnings := []string{}
+	switch status := i.lifecycleStatus(now); status {
+	case statusDeprecated, statusRetired, statusPlanned:
+		msg := fmt.Sprintf("Dependency '%s' uses %s interface '%s'", id, status, i.getID("."))
+		if i.replacedBy != nil {
+			msg += fmt.Sprintf(", use '%s' instead", i.replacedBy.getID("."))
+		}
+		warnings = append(warnings, msg)
+	}
+	if dep.version != "" && i.version != "" && dep.version != i.version {
+		warnings = append(warnings, fmt.Sprintf("Dependency '%s' pins version '%s' but interface '%s' provides version '%s'", id, dep.version, i.getID("."), i.version))
+	}
+	if dep.protocol != "" && i.protocol != "" && dep.protocol != i.protocol {
+		warnings = append(warnings, fmt.Sprintf("Dependency '%s' uses protocol '%s' but interface '%s' provides protocol '%s'", id, dep.protocol, i.getID("."), i.protocol))
+	}
+	return warnings
+}
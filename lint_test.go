@@ -0,0 +1,68 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLifecycleStatus(t *testing.T) {
+	now, _ := time.Parse(dateFormat, "2024-06-01")
+	tests := []struct {
+		c    interfConfiguration
+		want string
+	}{
+		{c: interfConfiguration{}, want: statusActive},
+		{c: interfConfiguration{Status: statusPlanned}, want: statusPlanned},
+		{c: interfConfiguration{DeprecatedAt: "2024-06-01"}, want: statusDeprecated},
+		{c: interfConfiguration{DeprecatedAt: "2024-07-01"}, want: statusActive},
+		{c: interfConfiguration{Status: statusDeprecated, RetiredAt: "2024-01-01"}, want: statusRetired},
+		{c: interfConfiguration{Status: statusRetired, DeprecatedAt: "2023-01-01"}, want: statusRetired},
+	}
+	for _, tc := range tests {
+		got := newInterf("IF", tc.c, nil).lifecycleStatus(now)
+		if got != tc.want {
+			t.Errorf("%+v: expected '%s', got '%s'", tc.c, tc.want, got)
+		}
+	}
+}
+
+func TestLint(t *testing.T) {
+	root := newElement("root", elementConfiguration{})
+	a := newElement("A", elementConfiguration{
+		Interfaces: map[string]interfConfiguration{
+			"OLD": {Status: statusDeprecated, ReplacedBy: "A.NEW", Version: "v1"},
+			"NEW": {Version: "v2", Protocol: "grpc"},
+		},
+	})
+	b := newElement("B", elementConfiguration{
+		Dependencies: map[string]dependencyConfiguration{
+			"ToOld": {DependsOn: "A.OLD", Version: "v1"},
+			"ToNew": {DependsOn: "A.NEW", Version: "v1", Protocol: "http"},
+		},
+	})
+	for _, e := range []*element{a, b} {
+		err := root.appendAt(e, []string{e.fragment})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	errs := root.resolveDependencies(root)
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	warnings := root.lint(time.Now())
+	want := map[string]bool{
+		"Dependency 'B.ToOld' uses deprecated interface 'A.OLD', use 'A.NEW' instead":              true,
+		"Dependency 'B.ToNew' pins version 'v1' but interface 'A.NEW' provides version 'v2'":       true,
+		"Dependency 'B.ToNew' uses protocol 'http' but interface 'A.NEW' provides protocol 'grpc'": true,
+	}
+	if len(warnings) != len(want) {
+		t.Fatalf("expected %d warnings, got %v", len(want), warnings)
+	}
+	for _, w := range warnings {
+		if !want[w] {
+			t.Errorf("unexpected warning: %s", w)
+		}
+	}
+}
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/util"
+	"gopkg.in/yaml.v3"
+)
+
+// referenceChange is a reference which is rewritten by a move
+type referenceChange struct {
+	File string `json:"file"`
+	Key  string `json:"key"`
+	Old  string `json:"old"`
+	New  string `json:"new"`
+
+	path []string
+}
+
+type moveReport struct {
+	From    string            `json:"from"`
+	To      string            `json:"to"`
+	Changes []referenceChange `json:"changes"`
+	dryRun  bool
+}
+
+// move moves the directory of the element with the ID from so that it becomes the element
+// with the ID to and rewrites all references affected by the move in the definition files.
+// If dryRun is set, the changes are only reported. It needs to be called on a tree with
+// resolved dependencies.
+func (root *element) move(from, to string, filesys billy.Filesystem, dryRun bool) (moveReport, error) {
+	r := moveReport{Changes: []referenceChange{}, dryRun: dryRun}
+	oldPos, newPos := positionFromID(from, "."), positionFromID(to, ".")
+	if len(oldPos) == 0 || len(newPos) == 0 {
+		return r, fmt.Errorf("The root element cannot be moved")
+	}
+	e, err := root.findElementByPosition(oldPos)
+	if err != nil {
+		return r, err
+	}
+	if e.dir == "" {
+		return r, fmt.Errorf("Element '%s' is defined inline and has no directory to be moved", from)
+	}
+	if hasPrefix(newPos, oldPos) {
+		return r, fmt.Errorf("Element '%s' cannot be moved below itself", from)
+	}
+	if _, err := root.findElementByPosition(newPos); err == nil {
+		return r, fmt.Errorf("Element '%s' already exists", to)
+	}
+	parent, err := root.findElementByPosition(newPos[:len(newPos)-1])
+	if err != nil {
+		return r, fmt.Errorf("Could not find parent of '%s': %w", to, err)
+	}
+	if parent.dir == "" {
+		return r, fmt.Errorf("Element '%s' is defined inline, elements cannot be moved below it", parent.getID("."))
+	}
+	r.From, r.To = e.dir, filepath.Join(parent.dir, newPos[len(newPos)-1])
+	if _, err := filesys.Stat(r.To); err == nil {
+		return r, fmt.Errorf("'%s' already exists", r.To)
+	}
+
+	aliases, errs := root.aliases()
+	if len(errs) > 0 {
+		return r, errors.Join(errs...)
+	}
+	moved := func(pos []string) []string {
+		if !hasPrefix(pos, oldPos) {
+			return pos
+		}
+		return append(append([]string{}, newPos...), pos[len(oldPos):]...)
+	}
+	for _, x := range root.getElements() {
+		file, keys := x.source()
+		refs := x.declaredReferences()
+		for _, key := range sortedKeys(refs) {
+			raw := refs[key]
+			rewritten, err := rewriteReference(raw, x, aliases, moved)
+			if err != nil {
+				return r, err
+			}
+			if rewritten == raw {
+				continue
+			}
+			path := append(append([]string{}, keys...), strings.Split(key, "/")...)
+			r.Changes = append(r.Changes, referenceChange{File: file, Key: strings.Join(path, "."), Old: raw, New: rewritten, path: path})
+		}
+	}
+
+	// rewrite all files before moving the directory as the paths change with the move
+	files := map[string][]referenceChange{}
+	for _, c := range r.Changes {
+		files[c.File] = append(files[c.File], c)
+	}
+	for _, file := range sortedKeys(files) {
+		data, err := readFile(file, filesys)
+		if err != nil {
+			return r, err
+		}
+		data, err = rewriteDefinition(file, data, files[file])
+		if err != nil {
+			return r, fmt.Errorf("Could not rewrite '%s': %w", file, err)
+		}
+		if dryRun {
+			continue
+		}
+		info, err := filesys.Stat(file)
+		if err != nil {
+			return r, err
+		}
+		err = util.WriteFile(filesys, file, data, info.Mode())
+		if err != nil {
+			return r, fmt.Errorf("Could not write '%s': %w", file, err)
+		}
+	}
+	if dryRun {
+		return r, nil
+	}
+	err = filesys.Rename(r.From, r.To)
+	if err != nil {
+		return r, fmt.Errorf("Could not move '%s' to '%s': %w", r.From, r.To, err)
+	}
+	return r, nil
+}
+
+// source returns the definition file of the element along with the keys leading to the
+// definition of the element within this file
+func (e *element) source() (string, []string) {
+	if e.dir != "" || e.parent == nil {
+		return e.file, []string{}
+	}
+	file, keys := e.parent.source()
+	return file, append(keys, "children", e.fragment)
+}
+
+// declaredReferences returns all references declared by the element by their key within
+// the definition, where the segments of the key are separated by '/'
+func (e *element) declaredReferences() map[string]string {
+	out := map[string]string{}
+	for _, d := range e.dependencies {
+		if d.belongsTo == e {
+			out[fmt.Sprintf("dependencies/%s/depends_on", d.fragment)] = d.reference
+		}
+	}
+	for _, i := range e.interfaces {
+		if i.belongsTo == e && i.replacement != "" {
+			out[fmt.Sprintf("interfaces/%s/replaced_by", i.fragment)] = i.replacement
+		}
+	}
+	return out
+}
+
+// rewriteReference returns the reference declared by the element from after a move, where
+// moved maps positions before the move to positions after the move. Relative references
+// stay relative and aliases are not affected by moves.
+func rewriteReference(raw string, from *element, aliases map[string]*element, moved func([]string) []string) (string, error) {
+	if strings.HasPrefix(raw, "@") {
+		return raw, nil
+	}
+	target, err := referencePosition(raw, from, aliases)
+	if err != nil {
+		return raw, err
+	}
+	newTarget := moved(target)
+	if !strings.HasPrefix(raw, ".") {
+		if equalPosition(target, newTarget) {
+			return raw, nil
+		}
+		return strings.Join(newTarget, "."), nil
+	}
+	base := moved(from.position()[1:])
+	up, rest := relativeReference(raw)
+	if up <= len(base) && equalPosition(append(append([]string{}, base[:len(base)-up]...), positionFromID(rest, ".")...), newTarget) {
+		return raw, nil
+	}
+	common := 0
+	for common < len(base) && common < len(newTarget) && base[common] == newTarget[common] {
+		common++
+	}
+	up, rest = len(base)-common, strings.Join(newTarget[common:], ".")
+	if strings.Contains(raw, "/") {
+		if up == 0 {
+			return "./" + rest, nil
+		}
+		return strings.TrimSuffix(strings.Repeat("../", up)+rest, "/"), nil
+	}
+	return strings.Repeat(".", up+1) + rest, nil
+}
+
+// rewriteDefinition replaces the values of the changed references in the content of a
+// definition file, everything else including the Markdown body is preserved
+func rewriteDefinition(file string, data []byte, changes []referenceChange) ([]byte, error) {
+	lines := strings.Split(string(data), "\n")
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".toml":
+		// values are replaced wherever they appear, a value can therefore only be changed once
+		applied := map[string]string{}
+		for _, c := range changes {
+			if replaced, ok := applied[c.Old]; ok {
+				if replaced != c.New {
+					return nil, fmt.Errorf("Value '%s' needs to be changed to both '%s' and '%s'", c.Old, replaced, c.New)
+				}
+				continue
+			}
+			applied[c.Old] = c.New
+			key := regexp.QuoteMeta(c.path[len(c.path)-1])
+			re := regexp.MustCompile(fmt.Sprintf(`(\b%s\s*=\s*)(["'])%s["']`, key, regexp.QuoteMeta(c.Old)))
+			found := false
+			for n, line := range lines {
+				if re.MatchString(line) {
+					lines[n] = re.ReplaceAllString(line, "${1}${2}"+c.New+"${2}")
+					found = true
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("Could not find '%s'", c.Key)
+			}
+		}
+		return []byte(strings.Join(lines, "\n")), nil
+	case ".yaml", ".yml", ".json":
+		return rewriteYAML(lines, 0, len(lines), changes)
+	}
+	start, end, err := frontmatterLines(lines)
+	if err != nil {
+		return nil, err
+	}
+	return rewriteYAML(lines, start, end, changes)
+}
+
+func rewriteYAML(lines []string, start, end int, changes []referenceChange) ([]byte, error) {
+	doc := yaml.Node{}
+	err := yaml.Unmarshal([]byte(strings.Join(lines[start:end], "\n")+"\n"), &doc)
+	if err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return nil, fmt.Errorf("Document is empty")
+	}
+	for _, c := range changes {
+		n := lookupNode(doc.Content[0], c.path)
+		if n == nil || n.Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("Could not find '%s'", c.Key)
+		}
+		quote := ""
+		switch n.Style {
+		case yaml.DoubleQuotedStyle:
+			quote = `"`
+		case yaml.SingleQuotedStyle:
+			quote = "'"
+		}
+		line := []rune(lines[start+n.Line-1])
+		col := n.Column - 1
+		old := quote + c.Old + quote
+		if col > len(line) || !strings.HasPrefix(string(line[col:]), old) {
+			return nil, fmt.Errorf("Could not find value '%s' of '%s'", c.Old, c.Key)
+		}
+		lines[start+n.Line-1] = string(line[:col]) + quote + c.New + quote + string(line[col:])[len(old):]
+	}
+	return []byte(strings.Join(lines, "\n")), nil
+}
+
+// lookupNode returns the value found by following the keys of nested mappings
+func lookupNode(n *yaml.Node, path []string) *yaml.Node {
+	if len(path) == 0 {
+		return n
+	}
+	if n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == path[0] {
+			return lookupNode(n.Content[i+1], path[1:])
+		}
+	}
+	return nil
+}
+
+func hasPrefix(pos, prefix []string) bool {
+	return len(pos) >= len(prefix) && equalPosition(pos[:len(prefix)], prefix)
+}
+
+func equalPosition(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Write prints the report in the given format
+func (r moveReport) Write(w io.Writer, format string) error {
+	switch format {
+	case "table":
+		verb := "Moved"
+		if r.dryRun {
+			verb = "Would move"
+		}
+		fmt.Fprintf(w, "%s '%s' to '%s'\n", verb, r.From, r.To)
+		if len(r.Changes) == 0 {
+			return nil
+		}
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "FILE\tKEY\tOLD\tNEW")
+		for _, c := range r.Changes {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", c.File, c.Key, c.Old, c.New)
+		}
+		return tw.Flush()
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	}
+	return fmt.Errorf("Output format '%s' is not supported, please choose one of [table json]", format)
+}
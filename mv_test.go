@@ -0,0 +1,81 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+)
+
+func TestMove(t *testing.T) {
+	files := map[string]string{
+		"README.md":               "---\nname: Root\n---\n",
+		"BE/README.md":            "---\nname: Backend\n---\n",
+		"BE/DB/README.md":         "---\ninterfaces:\n  SQL: {}\n---\n# Database\n\nKeep this body.\n",
+		"BE/SVC/README.md":        "---\n# keep comments\ninterfaces:\n  API: {}\ndependencies:\n  ToDB:\n    depends_on: \"..DB.SQL\"\n  ToWorker:\n    depends_on: ./worker.Q\n---\n",
+		"BE/SVC/worker/README.md": "---\ninterfaces:\n  Q: {}\ndependencies:\n  ToDB:\n    depends_on: '../../DB.SQL'\n---\n",
+		"FE/sysdoc.json":          "{\n  \"dependencies\": {\n    \"ToAPI\": {\"depends_on\": \"BE.SVC.API\"}\n  }\n}\n",
+		"OPS/sysdoc.toml":         "[children.cron.dependencies.ToDB]\ndepends_on = \"BE.DB.SQL\"\n\n[dependencies.ToSVC]\ndepends_on = 'BE.SVC'\n",
+	}
+	fs := memfs.New()
+	writeTestFiles(t, fs, files)
+	load := func() *element {
+		return loadTestSystem(t, fs, ".", []string{"README.md", "sysdoc.*"}, config{})
+	}
+
+	r, err := load().move("BE.SVC", "PLATFORM", fs, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"BE/SVC/README.md.dependencies.ToDB.depends_on":        "..BE.DB.SQL",
+		"BE/SVC/worker/README.md.dependencies.ToDB.depends_on": "../../BE.DB.SQL",
+		"FE/sysdoc.json.dependencies.ToAPI.depends_on":         "PLATFORM.API",
+		"OPS/sysdoc.toml.dependencies.ToSVC.depends_on":        "PLATFORM",
+	}
+	got := map[string]string{}
+	for _, c := range r.Changes {
+		got[c.File+"."+c.Key] = c.New
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("%s: expected '%s', got '%s'", key, value, got[key])
+		}
+	}
+	if _, err := fs.Stat("PLATFORM"); err == nil {
+		t.Errorf("expected dry run not to move the directory")
+	}
+
+	_, err = load().move("BE.DB", "OPS.DB", fs, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sys := load()
+	if _, err := sys.findElementByPosition([]string{"OPS", "DB"}); err != nil {
+		t.Error(err)
+	}
+	expected := map[string]string{
+		"OPS/DB/README.md":        files["BE/DB/README.md"],
+		"BE/SVC/README.md":        "---\n# keep comments\ninterfaces:\n  API: {}\ndependencies:\n  ToDB:\n    depends_on: \"...OPS.DB.SQL\"\n  ToWorker:\n    depends_on: ./worker.Q\n---\n",
+		"BE/SVC/worker/README.md": "---\ninterfaces:\n  Q: {}\ndependencies:\n  ToDB:\n    depends_on: '../../../OPS.DB.SQL'\n---\n",
+		"OPS/sysdoc.toml":         "[children.cron.dependencies.ToDB]\ndepends_on = \"OPS.DB.SQL\"\n\n[dependencies.ToSVC]\ndepends_on = 'BE.SVC'\n",
+	}
+	for name, content := range expected {
+		data, err := util.ReadFile(fs, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != content {
+			t.Errorf("%s: expected:\n%s\ngot:\n%s", name, content, data)
+		}
+	}
+
+	for _, tc := range [][2]string{{"BE", "BE.SVC.X"}, {"BE.SVC", "FE"}, {"OPS.cron", "X"}, {"BE.SVC", "NOPE.SVC"}} {
+		if _, err := load().move(tc[0], tc[1], fs, true); err == nil {
+			t.Errorf("expected moving '%s' to '%s' to fail", tc[0], tc[1])
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/util"
+)
+
+var outputFormats = []string{"rendered", "source"}
+
+// outputConfig declares a diagram which is generated by 'sysdoc generate'
+type outputConfig struct {
+	Renderer string   `yaml:"renderer"`
+	Focus    []string `yaml:"focus"`
+	Query    string   `yaml:"query"`
+	Owner    string   `yaml:"owner"`
+	View     string   `yaml:"view"`
+	Depth    int      `yaml:"depth"`
+	Path     string   `yaml:"path"`
+	// Format is either 'rendered' to run the postprocessor of the renderer, which is the
+	// default, or 'source' to write the output of the templates only
+	Format string `yaml:"format"`
+	// PerElement generates one diagram focussed on each element instead, or on each element
+	// matching the query. The diagrams are named File and written to a directory tree below
+	// Path mirroring the element positions, or next to the definitions if Path is empty.
+	PerElement bool   `yaml:"per_element"`
+	File       string `yaml:"file"`
+
+	// filesys is set for outputs written next to the definitions
+	filesys billy.Filesystem
+}
+
+func (o outputConfig) validate(renderers map[string]renderConfig) error {
+	if o.PerElement {
+		if o.File == "" {
+			return fmt.Errorf("File is missing, it is required for outputs per element")
+		}
+		if len(o.Focus) > 0 || o.Owner != "" {
+			return fmt.Errorf("Focus and owner cannot be combined with outputs per element, please select the elements with a query")
+		}
+	} else if o.Path == "" {
+		return fmt.Errorf("Path is missing")
+	}
+	if _, ok := renderers[o.Renderer]; !ok {
+		return fmt.Errorf("Renderer '%s' is not specified, please choose one of %v", o.Renderer, sortedKeys(renderers))
+	}
+	if o.Format != "" && !contains(outputFormats, o.Format) {
+		return fmt.Errorf("Format '%s' is not valid, please choose one of %v", o.Format, outputFormats)
+	}
+	if o.View != "" && !contains(views, o.View) {
+		return fmt.Errorf("View '%s' is not valid, please choose one of %v", o.View, views)
+	}
+	if o.Depth < 0 {
+		return fmt.Errorf("Depth must not be negative")
+	}
+	return nil
+}
+
+func (o outputConfig) filter() filter {
+	return filter{Focus: o.Focus, Query: o.Query, Owner: o.Owner, View: o.View, Depth: o.Depth}
+}
+
+// expand returns an output focussed on each selected element for outputs per element, other
+// outputs are returned as they are
+func (o outputConfig) expand(sys *element, filesys billy.Filesystem) ([]outputConfig, error) {
+	if !o.PerElement {
+		return []outputConfig{o}, nil
+	}
+	elems := sys.getElements()
+	if o.Query != "" {
+		q, err := newQuery(o.Query)
+		if err != nil {
+			return nil, err
+		}
+		elems = q.Select(sys)
+	}
+	out := []outputConfig{}
+	for _, e := range elems {
+		x := o
+		x.PerElement, x.Query, x.File = false, "", ""
+		x.Focus = []string{e.getID(".")}
+		if o.Path == "" {
+			x.Path, x.filesys = e.besideDefinition(o.File), filesys
+		} else {
+			x.Path = filepath.Join(append(append([]string{o.Path}, e.position()[1:]...), o.File)...)
+		}
+		out = append(out, x)
+	}
+	return out, nil
+}
+
+// besideDefinition returns the path of the file next to the definition of the element,
+// inline children are prefixed with their position relative to the defining element
+func (e *element) besideDefinition(file string) string {
+	d, rel := e, []string{}
+	for d.dir == "" && d.parent != nil {
+		rel = append([]string{d.fragment}, rel...)
+		d = d.parent
+	}
+	if len(rel) > 0 {
+		file = strings.Join(rel, ".") + "." + file
+	}
+	return filepath.Join(d.dir, file)
+}
+
+// generate renders all outputs of the configuration in parallel based on the loaded system,
+// which is not altered. It returns the written paths and an error for every failed output.
+// Outputs next to the definitions are written to filesys.
+func (r *Renderer) generate(sys *element, cfg config, filesys billy.Filesystem) ([]string, []error) {
+	outputs := []outputConfig{}
+	for _, o := range cfg.Outputs {
+		expanded, err := o.expand(sys, filesys)
+		if err != nil {
+			return nil, []error{fmt.Errorf("Could not expand outputs per element: %w", err)}
+		}
+		outputs = append(outputs, expanded...)
+	}
+
+	paths := make([]string, len(outputs))
+	errs := make([]error, len(outputs))
+	// limit the number of outputs rendered at once as outputs per element can be many
+	limit := make(chan struct{}, runtime.NumCPU())
+	var wg sync.WaitGroup
+	for n, o := range outputs {
+		wg.Add(1)
+		go func(n int, o outputConfig) {
+			defer wg.Done()
+			limit <- struct{}{}
+			defer func() { <-limit }()
+			errs[n] = r.generateOutput(sys.clone(), o, cfg)
+			if errs[n] != nil {
+				errs[n] = fmt.Errorf("Could not generate '%s': %w", o.Path, errs[n])
+				return
+			}
+			paths[n] = o.Path
+		}(n, o)
+	}
+	wg.Wait()
+
+	written, failed := []string{}, []error{}
+	for n := range outputs {
+		if errs[n] != nil {
+			failed = append(failed, errs[n])
+			continue
+		}
+		written = append(written, paths[n])
+	}
+	return written, failed
+}
+
+func (r *Renderer) generateOutput(sys *element, o outputConfig, cfg config) error {
+	err := sys.apply(o.filter(), cfg)
+	if err != nil {
+		return err
+	}
+	data, err := r.Do(sys, cfg.Renderer[o.Renderer], o.Format == "source")
+	if err != nil {
+		return err
+	}
+	if o.filesys != nil {
+		return util.WriteFile(o.filesys, o.Path, data, 0644)
+	}
+	err = os.MkdirAll(filepath.Dir(o.Path), 0755)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(o.Path, data, 0644)
+}
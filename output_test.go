@@ -0,0 +1,112 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sysdoc/internal/persistence"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/util"
+)
+
+func TestGenerate(t *testing.T) {
+	p, err := persistence.NewLocal("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sys, errs := loadSystem(".", []string{"README.md"}, config{}, p)
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	before := len(sys.getElements())
+
+	dir := t.TempDir()
+	cfg := config{
+		Renderer: map[string]renderConfig{"default": idRenderer()},
+		Outputs: []outputConfig{
+			{Renderer: "default", Path: filepath.Join(dir, "all.txt")},
+			{Renderer: "default", Path: filepath.Join(dir, "nested", "depth.txt"), Depth: 1},
+			{Renderer: "default", Path: filepath.Join(dir, "db.txt"), Query: "BE.DB", Format: "source"},
+			{Renderer: "default", Path: filepath.Join(dir, "missing.txt"), Query: "X"},
+		},
+	}
+	written, errs := NewRenderer().generate(sys, cfg, p.Filesystem())
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "missing.txt") {
+		t.Errorf("expected a single error for missing.txt, got %v", errs)
+	}
+	if len(written) != 3 {
+		t.Errorf("expected 3 written outputs, got %v", written)
+	}
+	if got := len(sys.getElements()); got != before {
+		t.Errorf("expected the loaded system to stay unchanged with %d elements, got %d", before, got)
+	}
+
+	want := map[string]string{
+		"all.txt":          ";BE;BE.DB;BE.SVC;FE;FE.WEB;",
+		"nested/depth.txt": ";BE;FE;",
+		"db.txt":           ";BE;BE.DB;BE.SVC;",
+	}
+	for file, expected := range want {
+		data, err := os.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if string(data) != expected {
+			t.Errorf("%s: expected '%s', got '%s'", file, expected, data)
+		}
+	}
+}
+
+func TestGeneratePerElement(t *testing.T) {
+	sys, filesys := loadMemSystem(t, map[string]string{
+		"README.md":        "---\nname: System\n---\n",
+		"BE/README.md":     "---\nname: Backend\nchildren:\n  DB:\n    name: Database\n---\n",
+		"BE/SVC/README.md": "---\nname: Service\ndependencies:\n  ToDB:\n    depends_on: BE.DB\n---\n",
+		"FE/README.md":     "---\nname: Frontend\n---\n",
+	})
+
+	dir := t.TempDir()
+	cfg := config{
+		Renderer: map[string]renderConfig{"default": idRenderer()},
+		Outputs: []outputConfig{
+			{Renderer: "default", PerElement: true, File: "context.txt"},
+			{Renderer: "default", PerElement: true, File: "context.txt", Path: dir, Query: "BE.**"},
+		},
+	}
+	written, errs := NewRenderer().generate(sys, cfg, filesys)
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	if len(written) != 8 {
+		t.Errorf("expected 8 written outputs, got %v", written)
+	}
+
+	beside := map[string]string{
+		"context.txt":        ";BE;BE.DB;BE.SVC;FE;",
+		"BE/context.txt":     ";BE;BE.DB;BE.SVC;",
+		"BE/DB.context.txt":  ";BE;BE.DB;BE.SVC;",
+		"BE/SVC/context.txt": ";BE;BE.DB;BE.SVC;",
+		"FE/context.txt":     ";FE;",
+	}
+	for file, expected := range beside {
+		data, err := util.ReadFile(filesys, file)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if string(data) != expected {
+			t.Errorf("%s: expected '%s', got '%s'", file, expected, data)
+		}
+	}
+	for _, file := range []string{"BE/context.txt", "BE/DB/context.txt", "BE/SVC/context.txt"} {
+		if _, err := os.Stat(filepath.Join(dir, file)); err != nil {
+			t.Error(err)
+		}
+	}
+
+	if err := (outputConfig{Renderer: "default", PerElement: true, File: "x", Owner: "team"}).validate(cfg.Renderer); err == nil {
+		t.Error("expected an error for an output per element with an owner")
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+type owner struct {
+	Team    string `yaml:"team" json:"team"`
+	Contact string `yaml:"contact" json:"contact"`
+	OnCall  string `yaml:"oncall" json:"oncall"`
+}
+
+// effectiveOwner returns the owner of the element, every field the element does not declare
+// itself is inherited from the parent elements
+func (e *element) effectiveOwner() owner {
+	o := e.owner
+	if e.parent == nil {
+		return o
+	}
+	inherited := e.parent.effectiveOwner()
+	if o.Team == "" {
+		o.Team = inherited.Team
+	}
+	if o.Contact == "" {
+		o.Contact = inherited.Contact
+	}
+	if o.OnCall == "" {
+		o.OnCall = inherited.OnCall
+	}
+	return o
+}
+
+// ownedBy returns the IDs of the topmost elements owned by the given team
+func (root *element) ownedBy(team string) []string {
+	out := []string{}
+	for _, e := range root.getElements() {
+		if e.parent == nil || e.effectiveOwner().Team != team {
+			continue
+		}
+		if e.parent.effectiveOwner().Team == team && e.parent.parent != nil {
+			continue
+		}
+		out = append(out, e.getID("."))
+	}
+	return out
+}
+
+type teamReport struct {
+	owner
+	Elements []string `json:"elements"`
+}
+
+type crossTeamDependency struct {
+	ConsumerTeam string `json:"consumer_team"`
+	Consumer     string `json:"consumer"`
+	ProviderTeam string `json:"provider_team"`
+	Interface    string `json:"interface"`
+}
+
+type ownersReport struct {
+	Teams     []teamReport          `json:"teams"`
+	CrossTeam []crossTeamDependency `json:"cross_team_dependencies"`
+	crossTeam bool
+}
+
+// newOwnersReport lists all teams with the elements they own as well as all dependencies
+// between elements of different teams. If team is not empty, only this team is reported.
+func newOwnersReport(root *element, team string, crossTeam bool) ownersReport {
+	r := ownersReport{
+		Teams:     []teamReport{},
+		CrossTeam: []crossTeamDependency{},
+		crossTeam: crossTeam,
+	}
+	teams := map[string]*teamReport{}
+	for _, e := range root.getElements() {
+		if e.parent == nil {
+			continue
+		}
+		o := e.effectiveOwner()
+		if team != "" && o.Team != team {
+			continue
+		}
+		if _, ok := teams[o.Team]; !ok {
+			teams[o.Team] = &teamReport{owner: o, Elements: []string{}}
+		}
+		teams[o.Team].Elements = append(teams[o.Team].Elements, e.getID("."))
+	}
+	for _, t := range teams {
+		sort.Strings(t.Elements)
+		r.Teams = append(r.Teams, *t)
+	}
+	sort.Slice(r.Teams, func(a, b int) bool { return r.Teams[a].Team < r.Teams[b].Team })
+
+	for _, dep := range root.getDependencies() {
+		if dep.provider() == nil {
+			continue
+		}
+		consumer := dep.belongsTo.effectiveOwner().Team
+		provider := dep.provider().effectiveOwner().Team
+		if consumer == provider {
+			continue
+		}
+		if team != "" && consumer != team && provider != team {
+			continue
+		}
+		r.CrossTeam = append(r.CrossTeam, crossTeamDependency{
+			ConsumerTeam: consumer,
+			Consumer:     dep.belongsTo.getID("."),
+			ProviderTeam: provider,
+			Interface:    dep.targetID("."),
+		})
+	}
+	sort.Slice(r.CrossTeam, func(a, b int) bool {
+		if r.CrossTeam[a].Interface != r.CrossTeam[b].Interface {
+			return r.CrossTeam[a].Interface < r.CrossTeam[b].Interface
+		}
+		return r.CrossTeam[a].Consumer < r.CrossTeam[b].Consumer
+	})
+	return r
+}
+
+// Write prints the report in the given format, table output contains either the teams or
+// the cross team dependencies whereas json always contains both
+func (r ownersReport) Write(w io.Writer, format string) error {
+	switch format {
+	case "table":
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		if r.crossTeam {
+			fmt.Fprintln(tw, "PROVIDER-TEAM\tINTERFACE\tCONSUMER-TEAM\tCONSUMER")
+			for _, d := range r.CrossTeam {
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", orNone(d.ProviderTeam), d.Interface, orNone(d.ConsumerTeam), d.Consumer)
+			}
+			return tw.Flush()
+		}
+		fmt.Fprintln(tw, "TEAM\tCONTACT\tON-CALL\tELEMENTS")
+		for _, t := range r.Teams {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", orNone(t.Team), t.Contact, t.OnCall, strings.Join(t.Elements, ", "))
+		}
+		return tw.Flush()
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	}
+	return fmt.Errorf("Output format '%s' is not supported, please choose one of [table json]", format)
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "(none)"
+	}
+	return s
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"reflect"
+	"sysdoc/internal/persistence"
+	"testing"
+)
+
+func TestOwnersReport(t *testing.T) {
+	p, err := persistence.NewLocal("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sys, errs := NewSystem(".", []string{"README.md"}, filter{}, config{}, p)
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	r := newOwnersReport(sys, "", false)
+	teams := map[string][]string{}
+	for _, team := range r.Teams {
+		teams[team.Team] = team.Elements
+	}
+	want := map[string][]string{
+		"backend":  {"BE", "BE.DB", "BE.SVC"},
+		"frontend": {"FE", "FE.WEB"},
+	}
+	if !reflect.DeepEqual(want, teams) {
+		t.Errorf("expected %v, got %v", want, teams)
+	}
+
+	r = newOwnersReport(sys, "backend", true)
+	wantCrossTeam := []crossTeamDependency{
+		{ConsumerTeam: "frontend", Consumer: "FE.WEB", ProviderTeam: "backend", Interface: "BE.SVC.API"},
+	}
+	if !reflect.DeepEqual(wantCrossTeam, r.CrossTeam) {
+		t.Errorf("expected %v, got %v", wantCrossTeam, r.CrossTeam)
+	}
+
+	if got := sys.ownedBy("frontend"); !reflect.DeepEqual([]string{"FE"}, got) {
+		t.Errorf("expected [FE], got %v", got)
+	}
+}
+
+func TestEffectiveOwner(t *testing.T) {
+	root := newElement("root", elementConfiguration{
+		Children: map[string]elementConfiguration{
+			"BE": {
+				Owner: owner{Team: "backend", Contact: "backend@example.com"},
+				Children: map[string]elementConfiguration{
+					"DB": {Owner: owner{OnCall: "db-oncall"}},
+				},
+			},
+		},
+	})
+	db, err := root.findElementByPosition([]string{"BE", "DB"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := owner{Team: "backend", Contact: "backend@example.com", OnCall: "db-oncall"}
+	if got := db.effectiveOwner(); got != want {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+	if got := root.ownedBy("backend"); !reflect.DeepEqual([]string{"BE"}, got) {
+		t.Errorf("expected [BE], got %v", got)
+	}
+}
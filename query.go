@@ -0,0 +1,367 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// A query selects a set of elements. Its syntax is as follows:
+//
+//	BE.SVC                 element with the given ID
+//	BE.*, BE.**            glob on IDs, '*' matches a single segment, '**' any number of segments
+//	tag(external)          elements which have the tag 'external' set
+//	tag(type=user)         elements which have the tag 'type' set to 'user'
+//	owner(team)            elements owned by the given team, including inherited ownership
+//	dependsOn(q)           elements which elements of q depend on
+//	dependents(q)          elements which depend on elements of q
+//	children(q)            direct children of elements of q
+//	descendants(q)         all children of elements of q, recursively
+//	parent(q)              direct parents of elements of q
+//	ancestors(q)           all parents of elements of q, recursively
+//	q | q                  union
+//	q & q                  intersection
+//	q - q                  difference
+//	(q)                    grouping
+//
+// Set operators are evaluated from left to right, parentheses can be used to alter the order.
+type query struct {
+	expr string
+	node queryNode
+}
+
+type queryNode func(root *element) map[*element]bool
+
+func newQuery(expr string) (query, error) {
+	q := query{expr: expr}
+	p := &queryParser{tokens: lexQuery(expr)}
+	if len(p.tokens) == 0 {
+		return q, fmt.Errorf("Query is empty")
+	}
+	node, err := p.parseExpr()
+	if err != nil {
+		return q, fmt.Errorf("Could not parse query '%s': %w", expr, err)
+	}
+	if !p.done() {
+		return q, fmt.Errorf("Could not parse query '%s': unexpected '%s'", expr, p.peek())
+	}
+	q.node = node
+	return q, nil
+}
+
+// Select returns all elements below root matching the query sorted by their ID
+func (q query) Select(root *element) []*element {
+	out := []*element{}
+	for e := range q.node(root) {
+		if e.parent == nil {
+			continue
+		}
+		out = append(out, e)
+	}
+	sort.Slice(out, func(a, b int) bool { return out[a].getID(".") < out[b].getID(".") })
+	return out
+}
+
+func lexQuery(expr string) []string {
+	tokens := []string{}
+	word := ""
+	flush := func() {
+		if word != "" {
+			tokens = append(tokens, word)
+			word = ""
+		}
+	}
+	for _, r := range expr {
+		switch {
+		case unicode.IsSpace(r):
+			flush()
+		case strings.ContainsRune("()|&,", r):
+			flush()
+			tokens = append(tokens, string(r))
+		default:
+			word += string(r)
+		}
+	}
+	flush()
+	return tokens
+}
+
+type queryParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *queryParser) done() bool { return p.pos >= len(p.tokens) }
+
+func (p *queryParser) peek() string {
+	if p.done() {
+		return ""
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *queryParser) next() string {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+func (p *queryParser) expect(t string) error {
+	if p.done() {
+		return fmt.Errorf("expected '%s' but query ended", t)
+	}
+	if got := p.next(); got != t {
+		return fmt.Errorf("expected '%s' but got '%s'", t, got)
+	}
+	return nil
+}
+
+func (p *queryParser) parseExpr() (queryNode, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for !p.done() {
+		op := p.peek()
+		if op != "|" && op != "&" && op != "-" {
+			break
+		}
+		p.next()
+		right, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		left = setOperation(op, left, right)
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseTerm() (queryNode, error) {
+	if p.done() {
+		return nil, fmt.Errorf("expected selector but query ended")
+	}
+	t := p.next()
+	switch t {
+	case "(":
+		node, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		return node, p.expect(")")
+	case ")", "|", "&", "-", ",":
+		return nil, fmt.Errorf("expected selector but got '%s'", t)
+	}
+	if p.peek() != "(" {
+		return globSelector(t), nil
+	}
+	p.next()
+	if t == "tag" || t == "owner" {
+		if p.done() {
+			return nil, fmt.Errorf("expected %s but query ended", t)
+		}
+		arg := p.next()
+		if t == "owner" {
+			return ownerSelector(arg), p.expect(")")
+		}
+		return tagSelector(arg), p.expect(")")
+	}
+	relation, ok := queryRelations[t]
+	if !ok {
+		list := []string{"owner", "tag"}
+		for key := range queryRelations {
+			list = append(list, key)
+		}
+		sort.Strings(list)
+		return nil, fmt.Errorf("unknown function '%s', please choose one of %v", t, list)
+	}
+	inner, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	return relationSelector(relation, inner), p.expect(")")
+}
+
+func setOperation(op string, left, right queryNode) queryNode {
+	return func(root *element) map[*element]bool {
+		l, r := left(root), right(root)
+		out := map[*element]bool{}
+		switch op {
+		case "|":
+			for e := range l {
+				out[e] = true
+			}
+			for e := range r {
+				out[e] = true
+			}
+		case "&":
+			for e := range l {
+				if r[e] {
+					out[e] = true
+				}
+			}
+		case "-":
+			for e := range l {
+				if !r[e] {
+					out[e] = true
+				}
+			}
+		}
+		return out
+	}
+}
+
+func globSelector(pattern string) queryNode {
+	pat := positionFromID(pattern, ".")
+	return func(root *element) map[*element]bool {
+		out := map[*element]bool{}
+		for _, e := range root.getElements() {
+			if e.parent != nil && matchPosition(pat, e.position()[1:]) {
+				out[e] = true
+			}
+		}
+		return out
+	}
+}
+
+// matchPosition matches a position against a pattern, where each segment of the pattern
+// is a glob as in path.Match or '**', which matches any number of segments
+func matchPosition(pattern, pos []string) bool {
+	if len(pattern) == 0 {
+		return len(pos) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(pos); i++ {
+			if matchPosition(pattern[1:], pos[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(pos) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(pattern[0], pos[0]); !ok {
+		return false
+	}
+	return matchPosition(pattern[1:], pos[1:])
+}
+
+func tagSelector(arg string) queryNode {
+	key, value, hasValue := strings.Cut(arg, "=")
+	return func(root *element) map[*element]bool {
+		out := map[*element]bool{}
+		for _, e := range root.getElements() {
+			v, ok := e.tags[key]
+			if ok && (!hasValue || v == value) {
+				out[e] = true
+			}
+		}
+		return out
+	}
+}
+
+func ownerSelector(team string) queryNode {
+	return func(root *element) map[*element]bool {
+		out := map[*element]bool{}
+		for _, e := range root.getElements() {
+			if e.effectiveOwner().Team == team {
+				out[e] = true
+			}
+		}
+		return out
+	}
+}
+
+var queryRelations = map[string]func(root, e *element) []*element{
+	"dependsOn": func(root, e *element) []*element {
+		out := []*element{}
+		for _, dep := range e.dependencies {
+			if dep.provider() != nil {
+				out = append(out, dep.provider())
+			}
+		}
+		return out
+	},
+	"dependents": func(root, e *element) []*element {
+		out := []*element{}
+		for _, dep := range root.getDependencies() {
+			if dep.provider() == e {
+				out = append(out, dep.belongsTo)
+			}
+		}
+		return out
+	},
+	"children": func(root, e *element) []*element {
+		return e.children
+	},
+	"descendants": func(root, e *element) []*element {
+		return e.getElements()[1:]
+	},
+	"parent": func(root, e *element) []*element {
+		if e.parent == nil {
+			return []*element{}
+		}
+		return []*element{e.parent}
+	},
+	"ancestors": func(root, e *element) []*element {
+		out := []*element{}
+		for p := e.parent; p != nil; p = p.parent {
+			out = append(out, p)
+		}
+		return out
+	},
+}
+
+func relationSelector(relation func(root, e *element) []*element, inner queryNode) queryNode {
+	return func(root *element) map[*element]bool {
+		out := map[*element]bool{}
+		for e := range inner(root) {
+			for _, related := range relation(root, e) {
+				out[related] = true
+			}
+		}
+		return out
+	}
+}
+
+type queryRecord struct {
+	ID           string                 `json:"id"`
+	Name         string                 `json:"name"`
+	Type         string                 `json:"type"`
+	Parent       string                 `json:"parent"`
+	Tags         map[string]string      `json:"tags"`
+	Owner        owner                  `json:"owner"`
+	Fields       map[string]interface{} `json:"fields"`
+	Children     []string               `json:"children"`
+	Interfaces   []string               `json:"interfaces"`
+	Dependencies map[string]string      `json:"dependencies"`
+}
+
+func newQueryRecord(e *element) queryRecord {
+	r := queryRecord{
+		ID:           e.getID("."),
+		Name:         e.name,
+		Type:         e.elementType,
+		Tags:         e.tags,
+		Owner:        e.effectiveOwner(),
+		Fields:       e.fields,
+		Children:     []string{},
+		Interfaces:   []string{},
+		Dependencies: map[string]string{},
+	}
+	if e.parent != nil {
+		r.Parent = e.parent.getID(".")
+	}
+	for _, child := range e.children {
+		r.Children = append(r.Children, child.getID("."))
+	}
+	for _, i := range e.interfaces {
+		r.Interfaces = append(r.Interfaces, i.getID("."))
+	}
+	for _, dep := range e.dependencies {
+		r.Dependencies[dep.fragment] = dep.reference
+	}
+	return r
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"reflect"
+	"sysdoc/internal/persistence"
+	"testing"
+)
+
+func TestMatchPosition(t *testing.T) {
+	tests := []struct {
+		pattern string
+		id      string
+		want    bool
+	}{
+		{pattern: "BE", id: "BE", want: true},
+		{pattern: "BE", id: "BE.SVC", want: false},
+		{pattern: "BE.*", id: "BE.SVC", want: true},
+		{pattern: "BE.*", id: "BE", want: false},
+		{pattern: "BE.**", id: "BE", want: true},
+		{pattern: "BE.**", id: "BE.SVC.X", want: true},
+		{pattern: "**.SVC", id: "BE.SVC", want: true},
+		{pattern: "B?", id: "BE", want: true},
+	}
+	for _, tc := range tests {
+		got := matchPosition(positionFromID(tc.pattern, "."), positionFromID(tc.id, "."))
+		if got != tc.want {
+			t.Errorf("pattern '%s' on '%s': expected %t, got %t", tc.pattern, tc.id, tc.want, got)
+		}
+	}
+}
+
+func TestQuerySelect(t *testing.T) {
+	p, err := persistence.NewLocal("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sys, errs := NewSystem(".", []string{"README.md"}, filter{}, config{}, p)
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	tests := []struct {
+		expr string
+		want []string
+	}{
+		{expr: "*", want: []string{"BE", "FE"}},
+		{expr: "**", want: []string{"BE", "BE.DB", "BE.SVC", "FE", "FE.WEB"}},
+		{expr: "BE.** - BE", want: []string{"BE.DB", "BE.SVC"}},
+		{expr: "BE.**-BE", want: []string{}},
+		{expr: "dependsOn(FE.WEB)", want: []string{"BE.SVC"}},
+		{expr: "dependents(BE.DB | BE.SVC)", want: []string{"BE.SVC", "FE.WEB"}},
+		{expr: "BE.* & dependsOn(**)", want: []string{"BE.DB", "BE.SVC"}},
+		{expr: "tag(link) | ancestors(FE.WEB)", want: []string{"BE.SVC", "FE"}},
+		{expr: "children(BE) - (tag(link=https://github.com/libsql/libsql))", want: []string{"BE.DB"}},
+		{expr: "tag(link=other)", want: []string{}},
+	}
+	for _, tc := range tests {
+		q, err := newQuery(tc.expr)
+		if err != nil {
+			t.Fatalf("query '%s': %s", tc.expr, err)
+		}
+		got := []string{}
+		for _, e := range q.Select(sys) {
+			got = append(got, e.getID("."))
+		}
+		if !reflect.DeepEqual(tc.want, got) {
+			t.Errorf("query '%s': expected %v, got %v", tc.expr, tc.want, got)
+		}
+	}
+}
+
+func TestQueryErrors(t *testing.T) {
+	for _, expr := range []string{"", "BE |", "(BE", "BE)", "unknown(BE)", "tag(", "| BE"} {
+		_, err := newQuery(expr)
+		if err == nil {
+			t.Errorf("expected error for query '%s'", expr)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// aliases returns all elements below root by their alias, an alias used by more than one
+// element is reported as error and not resolvable
+func (root *element) aliases() (map[string]*element, []error) {
+	errs := []error{}
+	out := map[string]*element{}
+	ambiguous := map[string]bool{}
+	for _, e := range root.getElements() {
+		if e.alias == "" {
+			continue
+		}
+		if other, ok := out[e.alias]; ok {
+			errs = append(errs, fmt.Errorf("Alias '%s' is ambiguous, it is used by '%s' and '%s'", e.alias, other.getID("."), e.getID(".")))
+			ambiguous[e.alias] = true
+			continue
+		}
+		out[e.alias] = e
+	}
+	for alias := range ambiguous {
+		delete(out, alias)
+	}
+	return out, errs
+}
+
+// referencePosition returns the absolute position of the interface a reference points to,
+// relative references are resolved against the element from. The syntax is as follows:
+//
+//	BE.SVC.API             absolute position of the interface
+//	.IF, ./IF              interface of the referencing element itself
+//	./child.IF             interface of a child of the referencing element
+//	..DB.SQL, ../DB.SQL    every further leading '.' moves up one parent
+//	../../DB.SQL           '..' can be repeated to move up further
+//	@payments.API          interface below the element with the alias 'payments'
+//
+// Aliases are declared with the 'alias' key and stay stable when elements are moved.
+func referencePosition(raw string, from *element, aliases map[string]*element) ([]string, error) {
+	switch {
+	case strings.HasPrefix(raw, "@"):
+		alias, rest, _ := strings.Cut(raw[1:], ".")
+		e, ok := aliases[alias]
+		if !ok {
+			return nil, fmt.Errorf("Alias '%s' not found", alias)
+		}
+		return append(e.position()[1:], positionFromID(rest, ".")...), nil
+	case strings.HasPrefix(raw, "."):
+		up, rest := relativeReference(raw)
+		e := from
+		for ; up > 0; up-- {
+			if e.parent == nil {
+				return nil, fmt.Errorf("Reference '%s' points above the root element", raw)
+			}
+			e = e.parent
+		}
+		return append(e.position()[1:], positionFromID(rest, ".")...), nil
+	}
+	return positionFromID(raw, "."), nil
+}
+
+// relativeReference splits a relative reference into the number of parents to move up and
+// the remaining position
+func relativeReference(raw string) (int, string) {
+	rest := strings.TrimLeft(raw, "./")
+	up := 0
+	for _, dots := range strings.Split(raw[:len(raw)-len(rest)], "/") {
+		if dots != "" {
+			up += len(dots) - 1
+		}
+	}
+	return up, rest
+}
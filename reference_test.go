@@ -0,0 +1,137 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResolveReferences(t *testing.T) {
+	root := newElement("root", elementConfiguration{
+		Children: map[string]elementConfiguration{
+			"BE": {
+				Alias: "backend",
+				Children: map[string]elementConfiguration{
+					"DB": {Interfaces: map[string]interfConfiguration{"SQL": {}}},
+					"SVC": {
+						Interfaces: map[string]interfConfiguration{"API": {}},
+						Dependencies: map[string]dependencyConfiguration{
+							"Sibling": {DependsOn: "..DB.SQL"},
+							"Child":   {DependsOn: "./worker.Q"},
+							"Self":    {DependsOn: ".API"},
+						},
+						Children: map[string]elementConfiguration{
+							"worker": {Interfaces: map[string]interfConfiguration{"Q": {}}},
+						},
+					},
+				},
+			},
+			"FE": {
+				Dependencies: map[string]dependencyConfiguration{
+					"Alias":    {DependsOn: "@backend.SVC.API"},
+					"Absolute": {DependsOn: "BE.DB.SQL"},
+					"Up":       {DependsOn: "../../DB.SQL"},
+				},
+			},
+		},
+	})
+	errs := root.resolveDependencies(root)
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "points above the root element") {
+		t.Fatalf("expected a single error for the reference above root, got %v", errs)
+	}
+	want := map[string]string{
+		"Sibling":  "BE.DB.SQL",
+		"Child":    "BE.SVC.worker.Q",
+		"Self":     "BE.SVC.API",
+		"Alias":    "BE.SVC.API",
+		"Absolute": "BE.DB.SQL",
+	}
+	for _, dep := range root.getDependencies() {
+		if dep.fragment == "Up" {
+			continue
+		}
+		if dep.dependsOn == nil {
+			t.Errorf("%s: expected '%s', got nothing", dep.fragment, want[dep.fragment])
+			continue
+		}
+		if got := dep.dependsOn.getID("."); got != want[dep.fragment] {
+			t.Errorf("%s: expected '%s', got '%s'", dep.fragment, want[dep.fragment], got)
+		}
+	}
+}
+
+func TestAmbiguousAlias(t *testing.T) {
+	root := newElement("root", elementConfiguration{
+		Children: map[string]elementConfiguration{
+			"A": {Alias: "x", Interfaces: map[string]interfConfiguration{"IF": {}}},
+			"B": {Alias: "x"},
+			"C": {Dependencies: map[string]dependencyConfiguration{
+				"Ambiguous": {DependsOn: "@x.IF"},
+				"Missing":   {DependsOn: "@y.IF"},
+			}},
+		},
+	})
+	errs := root.resolveDependencies(root)
+	msgs := []string{}
+	for _, err := range errs {
+		msgs = append(msgs, err.Error())
+	}
+	got := strings.Join(msgs, "\n")
+	for _, want := range []string{"Alias 'x' is ambiguous, it is used by 'A' and 'B'", "Alias 'x' not found", "Alias 'y' not found"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected error '%s', got:\n%s", want, got)
+		}
+	}
+}
+
+func TestDependencyOnElement(t *testing.T) {
+	root := newElement("root", elementConfiguration{
+		Children: map[string]elementConfiguration{
+			"BE": {
+				Interfaces: map[string]interfConfiguration{
+					"API": {},
+					"OLD": {Status: statusDeprecated},
+				},
+				Children: map[string]elementConfiguration{
+					"SVC": {
+						DefaultInterface: "HTTP",
+						Interfaces:       map[string]interfConfiguration{"HTTP": {}, "GRPC": {}},
+					},
+				},
+			},
+			"FE": {
+				Dependencies: map[string]dependencyConfiguration{
+					"Backend": {DependsOn: "BE"},
+					"Service": {DependsOn: "BE.SVC"},
+				},
+			},
+		},
+	})
+	errs := root.resolveDependencies(root)
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	deps := map[string]*dependency{}
+	for _, dep := range root.getDependencies() {
+		deps[dep.fragment] = dep
+	}
+	if d := deps["Backend"]; d.dependsOn != nil || d.provider().getID(".") != "BE" || len(d.providedInterfaces()) != 2 {
+		t.Errorf("expected dependency on element 'BE' with two interfaces, got %+v", d)
+	}
+	if d := deps["Service"]; d.dependsOn == nil || d.dependsOn.getID(".") != "BE.SVC.HTTP" {
+		t.Errorf("expected dependency to resolve to default interface 'BE.SVC.HTTP', got %+v", d)
+	}
+
+	warnings := root.lint(time.Now())
+	if len(warnings) != 1 || warnings[0] != "Dependency 'FE.Backend' uses deprecated interface 'BE.OLD'" {
+		t.Errorf("expected a warning for the deprecated interface, got %v", warnings)
+	}
+
+	if err := root.propagateInterfaces(); err != nil {
+		t.Fatal(err)
+	}
+	data := newDependencyTemplateData(deps["Backend"], "")
+	if got := data.ViaPropagation("."); got != "BE" || !data.OnElement() {
+		t.Errorf("expected edge to element 'BE', got '%s'", got)
+	}
+}
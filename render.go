@@ -6,6 +6,7 @@ import (
 	"sysdoc/internal/postprocessor"
 	"sysdoc/internal/postprocessor/d2"
 	"text/template"
+	"time"
 )
 
 // ELEMENT
@@ -23,6 +24,16 @@ func (e ElementTemplateData) Fragment() string        { return e.data.fragment }
 func (e ElementTemplateData) Name() string            { return e.data.name }
 func (e ElementTemplateData) Tags() map[string]string { return e.data.tags }
 func (e ElementTemplateData) Doc() string             { return string(e.data.doc) }
+func (e ElementTemplateData) Owner() owner            { return e.data.effectiveOwner() }
+func (e ElementTemplateData) Metrics() elementMetrics {
+	if e.data.metrics == nil {
+		return elementMetrics{}
+	}
+	return *e.data.metrics
+}
+func (e ElementTemplateData) DeclaredTags() map[string]string { return e.data.declaredTags }
+func (e ElementTemplateData) Fields() map[string]interface{}  { return e.data.fields }
+func (e ElementTemplateData) Type() string                    { return e.data.elementType }
 func (e ElementTemplateData) Children() []string {
 	out := []string{}
 	for _, child := range e.data.children {
@@ -70,6 +81,24 @@ func (i InterfaceTemplateData) Fragment() string               { return i.data.f
 func (i InterfaceTemplateData) ID(sep string) string           { return i.data.getID(sep) }
 func (i InterfaceTemplateData) PropagatesID(sep string) string { return i.data.propagates.getID(sep) }
 func (i InterfaceTemplateData) Tags() map[string]string        { return i.data.tags }
+func (i InterfaceTemplateData) Protocol() string               { return i.data.protocol }
+func (i InterfaceTemplateData) Version() string                { return i.data.version }
+func (i InterfaceTemplateData) Status() string                 { return i.data.lifecycleStatus(time.Now()) }
+func (i InterfaceTemplateData) DeprecatedAt() string           { return i.data.deprecatedAt }
+func (i InterfaceTemplateData) RetiredAt() string              { return i.data.retiredAt }
+func (i InterfaceTemplateData) ReplacedByID(sep string) string {
+	if i.data.replacedBy == nil {
+		return ""
+	}
+	return i.data.replacedBy.getID(sep)
+}
+func (i InterfaceTemplateData) DeclaredTags() map[string]string { return i.data.declaredTags }
+func (i InterfaceTemplateData) Metrics() interfMetrics {
+	if i.data.metrics == nil {
+		return interfMetrics{}
+	}
+	return *i.data.metrics
+}
 func (i InterfaceTemplateData) render() (string, error) {
 	var b bytes.Buffer
 	t, err := template.New("tmpl").Parse(i.templ)
@@ -101,12 +130,25 @@ func (d DependencyTemplateData) render() (string, error) {
 }
 func (d DependencyTemplateData) Fragment() string              { return d.data.fragment }
 func (d DependencyTemplateData) Description() string           { return d.data.description }
+func (d DependencyTemplateData) Kind() string                  { return d.data.kind }
+func (d DependencyTemplateData) Criticality() string           { return d.data.criticality }
+func (d DependencyTemplateData) Protocol() string              { return d.data.protocol }
+func (d DependencyTemplateData) Version() string               { return d.data.version }
 func (d DependencyTemplateData) Tags() map[string]string       { return d.data.tags }
 func (d DependencyTemplateData) BelongsToID(sep string) string { return d.data.belongsTo.getID(sep) }
-func (d DependencyTemplateData) DependsOnID(sep string) string { return d.data.dependsOn.getID(sep) }
+func (d DependencyTemplateData) DependsOnID(sep string) string { return d.data.targetID(sep) }
 func (d DependencyTemplateData) ViaPropagation(sep string) string {
+	if d.data.viaPropagation == nil {
+		return d.data.targetID(sep)
+	}
 	return d.data.viaPropagation.getID(sep)
 }
+func (d DependencyTemplateData) DeclaredTags() map[string]string { return d.data.declaredTags }
+
+// OnElement is true if the dependency points to a whole element instead of an interface
+func (d DependencyTemplateData) OnElement() bool {
+	return d.data.dependsOn == nil
+}
 
 // RENDER
 
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+type catalogueEntry struct {
+	Interface   string            `json:"interface"`
+	Name        string            `json:"name"`
+	Element     string            `json:"element"`
+	ElementName string            `json:"element_name"`
+	Team        string            `json:"team"`
+	Description string            `json:"description"`
+	Protocol    string            `json:"protocol"`
+	Version     string            `json:"version"`
+	Status      string            `json:"status"`
+	Tags        map[string]string `json:"tags"`
+	Consumers   []string          `json:"consumers"`
+}
+
+// dependencyMatrix lists by consumer and provider element the interfaces the consumer depends
+// on, dependencies on the whole provider are listed as 'element'
+type dependencyMatrix struct {
+	Consumers []string                       `json:"consumers"`
+	Providers []string                       `json:"providers"`
+	Cells     map[string]map[string][]string `json:"cells"`
+}
+
+type report struct {
+	Interfaces []catalogueEntry `json:"interfaces"`
+	Matrix     dependencyMatrix `json:"matrix"`
+}
+
+// newReport creates the interface catalogue and the dependency matrix of all elements
+func newReport(root *element, now time.Time) report {
+	r := report{
+		Interfaces: []catalogueEntry{},
+		Matrix: dependencyMatrix{
+			Consumers: []string{},
+			Providers: []string{},
+			Cells:     map[string]map[string][]string{},
+		},
+	}
+	consumers := map[*interf][]string{}
+	for _, dep := range root.getDependencies() {
+		provider := dep.provider()
+		if provider == nil {
+			continue
+		}
+		consumer, target := reportID(dep.belongsTo), "element"
+		if dep.dependsOn != nil {
+			consumers[dep.dependsOn] = append(consumers[dep.dependsOn], consumer)
+			target = dep.dependsOn.fragment
+		}
+		if _, ok := r.Matrix.Cells[consumer]; !ok {
+			r.Matrix.Cells[consumer] = map[string][]string{}
+		}
+		cell := r.Matrix.Cells[consumer][reportID(provider)]
+		if !contains(cell, target) {
+			r.Matrix.Cells[consumer][reportID(provider)] = append(cell, target)
+		}
+	}
+	providers := map[string]bool{}
+	for consumer, row := range r.Matrix.Cells {
+		r.Matrix.Consumers = append(r.Matrix.Consumers, consumer)
+		for provider, cell := range row {
+			providers[provider] = true
+			sort.Strings(cell)
+		}
+	}
+	sort.Strings(r.Matrix.Consumers)
+	r.Matrix.Providers = sortedKeys(providers)
+
+	for _, i := range root.getInterfaces() {
+		c := consumers[i]
+		if c == nil {
+			c = []string{}
+		}
+		sort.Strings(c)
+		tags := i.tags
+		if tags == nil {
+			tags = map[string]string{}
+		}
+		r.Interfaces = append(r.Interfaces, catalogueEntry{
+			Interface:   i.getID("."),
+			Name:        i.name,
+			Element:     reportID(i.belongsTo),
+			ElementName: i.belongsTo.name,
+			Team:        i.belongsTo.effectiveOwner().Team,
+			Description: i.description,
+			Protocol:    i.protocol,
+			Version:     i.version,
+			Status:      i.lifecycleStatus(now),
+			Tags:        tags,
+			Consumers:   c,
+		})
+	}
+	sort.Slice(r.Interfaces, func(a, b int) bool { return r.Interfaces[a].Interface < r.Interfaces[b].Interface })
+	return r
+}
+
+func (c catalogueEntry) elementLabel() string {
+	if c.ElementName == "" {
+		return c.Element
+	}
+	return fmt.Sprintf("%s (%s)", c.ElementName, c.Element)
+}
+
+// reportID returns the ID of the element, the root element is named '(root)'
+func reportID(e *element) string {
+	if e.parent == nil {
+		return "(root)"
+	}
+	return e.getID(".")
+}
+
+// Write prints the report in the given format
+func (r report) Write(w io.Writer, format string) error {
+	switch format {
+	case "markdown":
+		fmt.Fprintln(w, "# Interface Catalogue")
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "| Interface | Element | Team | Description | Protocol | Version | Status | Tags | Consumers |")
+		fmt.Fprintln(w, "|---|---|---|---|---|---|---|---|---|")
+		for _, i := range r.Interfaces {
+			fmt.Fprintf(w, "| %s |\n", strings.Join(markdownCells(
+				i.Interface, i.elementLabel(), i.Team, i.Description,
+				i.Protocol, i.Version, i.Status, formatTags(i.Tags), strings.Join(i.Consumers, ", "),
+			), " | "))
+		}
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "# Dependency Matrix")
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "The elements of the rows depend on the interfaces of the elements of the columns.")
+		fmt.Fprintln(w)
+		fmt.Fprintf(w, "| | %s |\n", strings.Join(markdownCells(r.Matrix.Providers...), " | "))
+		fmt.Fprintf(w, "|---|%s\n", strings.Repeat("---|", len(r.Matrix.Providers)))
+		for _, consumer := range r.Matrix.Consumers {
+			cells := []string{consumer}
+			for _, provider := range r.Matrix.Providers {
+				cells = append(cells, strings.Join(r.Matrix.Cells[consumer][provider], ", "))
+			}
+			fmt.Fprintf(w, "| %s |\n", strings.Join(markdownCells(cells...), " | "))
+		}
+		return nil
+	case "html":
+		return reportTemplate.Execute(w, r)
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	}
+	return fmt.Errorf("Output format '%s' is not supported, please choose one of [markdown html json]", format)
+}
+
+// markdownCells escapes the values to be used as cells of a Markdown table
+func markdownCells(values ...string) []string {
+	out := []string{}
+	for _, v := range values {
+		v = strings.ReplaceAll(v, "|", `\|`)
+		out = append(out, strings.Join(strings.Fields(v), " "))
+	}
+	return out
+}
+
+func formatTags(tags map[string]string) string {
+	out := []string{}
+	for _, k := range sortedKeys(tags) {
+		out = append(out, fmt.Sprintf("%s=%s", k, tags[k]))
+	}
+	return strings.Join(out, ", ")
+}
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"join": strings.Join,
+	"tags": formatTags,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>System Report</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+</style>
+</head>
+<body>
+<h1>Interface Catalogue</h1>
+<table>
+<tr><th>Interface</th><th>Element</th><th>Team</th><th>Description</th><th>Protocol</th><th>Version</th><th>Status</th><th>Tags</th><th>Consumers</th></tr>
+{{- range .Interfaces}}
+<tr><td>{{.Interface}}</td><td>{{if .ElementName}}{{.ElementName}} ({{.Element}}){{else}}{{.Element}}{{end}}</td><td>{{.Team}}</td><td>{{.Description}}</td><td>{{.Protocol}}</td><td>{{.Version}}</td><td>{{.Status}}</td><td>{{tags .Tags}}</td><td>{{join .Consumers ", "}}</td></tr>
+{{- end}}
+</table>
+<h1>Dependency Matrix</h1>
+<p>The elements of the rows depend on the interfaces of the elements of the columns.</p>
+<table>
+<tr><th></th>{{range .Matrix.Providers}}<th>{{.}}</th>{{end}}</tr>
+{{- $m := .Matrix}}
+{{- range $consumer := $m.Consumers}}
+<tr><th>{{$consumer}}</th>{{range $provider := $m.Providers}}<td>{{join (index $m.Cells $consumer $provider) ", "}}</td>{{end}}</tr>
+{{- end}}
+</table>
+</body>
+</html>
+`))
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReport(t *testing.T) {
+	root := newElement("root", elementConfiguration{
+		Children: map[string]elementConfiguration{
+			"BE": {
+				Owner: owner{Team: "backend"},
+				Name:  "Backend",
+				Children: map[string]elementConfiguration{
+					"DB": {Name: "Database", Interfaces: map[string]interfConfiguration{"SQL": {Description: "tables | views", Tags: map[string]string{"engine": "pg"}}}},
+					"SVC": {
+						Interfaces: map[string]interfConfiguration{"API": {Protocol: "https", DeprecatedAt: "2020-01-01"}},
+						Dependencies: map[string]dependencyConfiguration{
+							"ToDB": {DependsOn: "BE.DB.SQL"},
+						},
+					},
+				},
+			},
+			"FE": {
+				Dependencies: map[string]dependencyConfiguration{
+					"API":  {DependsOn: "BE.SVC.API"},
+					"Data": {DependsOn: "BE.DB"},
+					"SQL":  {DependsOn: "BE.DB.SQL"},
+				},
+			},
+		},
+	})
+	if errs := root.resolveDependencies(root); len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	r := newReport(root, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	if len(r.Interfaces) != 2 {
+		t.Fatalf("expected 2 interfaces, got %v", r.Interfaces)
+	}
+	sql := r.Interfaces[0]
+	if sql.Interface != "BE.DB.SQL" || sql.Team != "backend" || !reflect.DeepEqual([]string{"BE.SVC", "FE"}, sql.Consumers) {
+		t.Errorf("unexpected catalogue entry %+v", sql)
+	}
+	if api := r.Interfaces[1]; api.Status != statusDeprecated || !reflect.DeepEqual([]string{"FE"}, api.Consumers) {
+		t.Errorf("unexpected catalogue entry %+v", api)
+	}
+	if want := []string{"BE.SVC", "FE"}; !reflect.DeepEqual(want, r.Matrix.Consumers) {
+		t.Errorf("expected consumers %v, got %v", want, r.Matrix.Consumers)
+	}
+	if want := []string{"BE.DB", "BE.SVC"}; !reflect.DeepEqual(want, r.Matrix.Providers) {
+		t.Errorf("expected providers %v, got %v", want, r.Matrix.Providers)
+	}
+	if want := []string{"SQL", "element"}; !reflect.DeepEqual(want, r.Matrix.Cells["FE"]["BE.DB"]) {
+		t.Errorf("expected cell %v, got %v", want, r.Matrix.Cells["FE"]["BE.DB"])
+	}
+
+	var b bytes.Buffer
+	if err := r.Write(&b, "markdown"); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`| BE.DB.SQL | Database (BE.DB) | backend | tables \| views |  |  | active | engine=pg | BE.SVC, FE |`,
+		"| BE.SVC.API | BE.SVC | backend |  | https |  | deprecated |  | FE |",
+		"| | BE.DB | BE.SVC |\n|---|---|---|\n| BE.SVC | SQL |  |\n| FE | SQL, element | API |\n",
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("expected markdown to contain:\n%s\ngot:\n%s", want, b.String())
+		}
+	}
+
+	b.Reset()
+	if err := r.Write(&b, "html"); err != nil {
+		t.Fatal(err)
+	}
+	if want := "<tr><th>FE</th><td>SQL, element</td><td>API</td></tr>"; !strings.Contains(b.String(), want) {
+		t.Errorf("expected html to contain:\n%s\ngot:\n%s", want, b.String())
+	}
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/util"
+	"gopkg.in/yaml.v3"
+)
+
+// definitionFile returns the name of new definition files, which is the first glob without
+// any wildcards
+func definitionFile(globs []string) (string, error) {
+	for _, glob := range globs {
+		if !strings.ContainsAny(glob, `*?[\`) {
+			return glob, nil
+		}
+	}
+	return "", fmt.Errorf("None of the globs %v is a plain file name, please provide one to create definitions", globs)
+}
+
+// initBase creates a starter configuration file with the built in renderer as renderer
+// 'default' as well as a definition of the root element and returns the created files
+func initBase(basedir, configfile string, globs []string, filesys billy.Filesystem) ([]string, error) {
+	created := []string{}
+	def, err := definitionFile(globs)
+	if err != nil {
+		return created, err
+	}
+	if _, err := filesys.Stat(configfile); err == nil {
+		return created, fmt.Errorf("Configuration file '%s' already exists", configfile)
+	}
+	renderer, err := templateFS.ReadFile("server/private/renderer.yaml")
+	if err != nil {
+		return created, err
+	}
+	var b bytes.Buffer
+	b.WriteString("renderers:\n  default:\n")
+	for _, line := range strings.Split(strings.TrimRight(string(renderer), "\n"), "\n") {
+		if line == "---" {
+			continue
+		}
+		if line != "" {
+			line = "    " + line
+		}
+		b.WriteString(line + "\n")
+	}
+	err = util.WriteFile(filesys, configfile, b.Bytes(), 0644)
+	if err != nil {
+		return created, fmt.Errorf("Could not write '%s': %w", configfile, err)
+	}
+	created = append(created, configfile)
+
+	root := filepath.Join(basedir, def)
+	if _, err := filesys.Stat(root); err == nil {
+		return created, nil
+	}
+	data, err := newDefinition(root, mappingNode("name", "System"))
+	if err != nil {
+		return created, err
+	}
+	err = util.WriteFile(filesys, root, data, 0644)
+	if err != nil {
+		return created, fmt.Errorf("Could not write '%s': %w", root, err)
+	}
+	return append(created, root), nil
+}
+
+// addElement adds the element with the given ID and definition below its existing parent,
+// either in a new directory or inline if the parent is defined inline. It returns the
+// written file.
+func (root *element) addElement(id string, globs []string, definition *yaml.Node, filesys billy.Filesystem) (string, error) {
+	pos := positionFromID(id, ".")
+	if len(pos) == 0 {
+		return "", fmt.Errorf("The root element already exists")
+	}
+	if _, err := root.findElementByPosition(pos); err == nil {
+		return "", fmt.Errorf("Element '%s' already exists", id)
+	}
+	parent, err := root.findElementByPosition(pos[:len(pos)-1])
+	if err != nil {
+		return "", fmt.Errorf("Could not find parent of '%s': %w", id, err)
+	}
+	fragment := pos[len(pos)-1]
+	if parent.dir == "" {
+		file, keys := parent.source()
+		return file, updateDefinition(file, append(keys, "children", fragment), definition, filesys)
+	}
+	def, err := definitionFile(globs)
+	if err != nil {
+		return "", err
+	}
+	file := filepath.Join(parent.dir, fragment, def)
+	if _, err := filesys.Stat(filepath.Dir(file)); err == nil {
+		return "", fmt.Errorf("'%s' already exists", filepath.Dir(file))
+	}
+	data, err := newDefinition(file, definition)
+	if err != nil {
+		return "", err
+	}
+	return file, util.WriteFile(filesys, file, data, 0644)
+}
+
+// addInterface adds the interface with the given ID to the definition of its element and
+// returns the written file
+func (root *element) addInterface(id string, globs []string, definition *yaml.Node, filesys billy.Filesystem) (string, error) {
+	e, fragment, err := root.findOwnerOf(id)
+	if err != nil {
+		return "", err
+	}
+	for _, i := range e.interfaces {
+		if i.fragment == fragment {
+			return "", fmt.Errorf("Interface '%s' already exists", id)
+		}
+	}
+	return e.addToDefinition([]string{"interfaces", fragment}, globs, definition, filesys)
+}
+
+// addDependency adds the dependency with the given ID to the definition of its element
+// after validating that the reference can be resolved, it returns the written file
+func (root *element) addDependency(id, reference string, globs []string, definition *yaml.Node, filesys billy.Filesystem) (string, error) {
+	e, fragment, err := root.findOwnerOf(id)
+	if err != nil {
+		return "", err
+	}
+	for _, d := range e.dependencies {
+		if d.fragment == fragment {
+			return "", fmt.Errorf("Dependency '%s' already exists", id)
+		}
+	}
+	aliases, errs := root.aliases()
+	if len(errs) > 0 {
+		return "", errs[0]
+	}
+	_, _, err = root.findByReference(reference, e, aliases)
+	if err != nil {
+		return "", fmt.Errorf("Could not resolve dependency '%s': %w", reference, err)
+	}
+	return e.addToDefinition([]string{"dependencies", fragment}, globs, definition, filesys)
+}
+
+// findOwnerOf returns the element an interface or dependency with the given ID belongs to
+// along with the fragment of the interface or dependency
+func (root *element) findOwnerOf(id string) (*element, string, error) {
+	pos := positionFromID(id, ".")
+	if len(pos) == 0 {
+		return nil, "", fmt.Errorf("ID is empty")
+	}
+	e, err := root.findElementByPosition(pos[:len(pos)-1])
+	if err != nil {
+		return nil, "", err
+	}
+	return e, pos[len(pos)-1], nil
+}
+
+// addToDefinition adds the definition at the keys below the definition of the element,
+// elements without a definition file get one
+func (e *element) addToDefinition(keys []string, globs []string, definition *yaml.Node, filesys billy.Filesystem) (string, error) {
+	file, prefix := e.source()
+	if file != "" {
+		return file, updateDefinition(file, append(prefix, keys...), definition, filesys)
+	}
+	def, err := definitionFile(globs)
+	if err != nil {
+		return "", err
+	}
+	file = filepath.Join(e.dir, def)
+	data, err := newDefinition(file, nestedNode(keys, definition))
+	if err != nil {
+		return "", err
+	}
+	e.file = file
+	return file, util.WriteFile(filesys, file, data, 0644)
+}
+
+// updateDefinition inserts the definition at the keys of an existing definition file, the
+// rest of the file including comments and the Markdown body is preserved
+func updateDefinition(file string, keys []string, definition *yaml.Node, filesys billy.Filesystem) error {
+	data, err := readFile(file, filesys)
+	if err != nil {
+		return err
+	}
+	lines := strings.Split(string(data), "\n")
+	start, end := 0, len(lines)
+	plain := false
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml":
+		plain = true
+	case ".json", ".toml":
+		return fmt.Errorf("'%s' can not be edited, only Markdown and YAML definitions are supported", file)
+	default:
+		start, end, err = frontmatterLines(lines)
+		if err != nil {
+			return fmt.Errorf("'%s' can not be edited: %w", file, err)
+		}
+	}
+	doc := yaml.Node{}
+	err = yaml.Unmarshal([]byte(strings.Join(lines[start:end], "\n")+"\n"), &doc)
+	if err != nil {
+		return fmt.Errorf("Could not parse data of '%s', error occured: %w", file, err)
+	}
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	n := doc.Content[0]
+	for i, key := range keys {
+		if n.Kind != yaml.MappingNode {
+			return fmt.Errorf("Key '%s' of '%s' is not a mapping", strings.Join(keys[:i], "."), file)
+		}
+		next := lookupNode(n, []string{key})
+		if next == nil {
+			// empty mappings such as '{}' are continued in block style
+			if len(n.Content) == 0 {
+				n.Style = 0
+			}
+			n.Content = append(n.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, nestedNode(keys[i+1:], definition))
+			break
+		}
+		if i == len(keys)-1 {
+			return fmt.Errorf("Key '%s' already exists in '%s'", strings.Join(keys, "."), file)
+		}
+		n = next
+	}
+	encoded, err := encodeYAML(&doc)
+	if err != nil {
+		return err
+	}
+	if !plain {
+		out := append([]string{lines[0], strings.TrimSuffix(encoded, "\n")}, lines[end:]...)
+		encoded = strings.Join(out, "\n")
+	}
+	info, err := filesys.Stat(file)
+	if err != nil {
+		return err
+	}
+	return util.WriteFile(filesys, file, []byte(encoded), info.Mode())
+}
+
+// newDefinition returns the content of a new definition file depending on its extension
+func newDefinition(file string, definition *yaml.Node) ([]byte, error) {
+	encoded, err := encodeYAML(definition)
+	if err != nil {
+		return nil, err
+	}
+	if len(definition.Content) == 0 {
+		encoded = ""
+	}
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml":
+		return []byte(encoded), nil
+	case ".json", ".toml":
+		return nil, fmt.Errorf("'%s' can not be created, only Markdown and YAML definitions are supported", file)
+	}
+	return []byte("---\n" + encoded + "---\n"), nil
+}
+
+// frontmatterLines returns the range of lines containing the YAML header of a Markdown file
+func frontmatterLines(lines []string) (int, int, error) {
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) != "---" {
+		return 0, 0, fmt.Errorf("Only YAML front matter is supported")
+	}
+	for end := 1; end < len(lines); end++ {
+		if strings.TrimSpace(lines[end]) == "---" {
+			return 1, end, nil
+		}
+	}
+	return 0, 0, fmt.Errorf("Front matter is not terminated")
+}
+
+func encodeYAML(n *yaml.Node) (string, error) {
+	var b bytes.Buffer
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	err := enc.Encode(n)
+	if err != nil {
+		return "", err
+	}
+	return b.String(), enc.Close()
+}
+
+// mappingNode returns a mapping of the given keys and values, empty values are omitted
+func mappingNode(pairs ...string) *yaml.Node {
+	n := &yaml.Node{Kind: yaml.MappingNode}
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if pairs[i+1] == "" {
+			continue
+		}
+		n.Content = append(n.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: pairs[i]}, &yaml.Node{Kind: yaml.ScalarNode, Value: pairs[i+1]})
+	}
+	return n
+}
+
+// nestedNode wraps the node in a mapping for every key
+func nestedNode(keys []string, n *yaml.Node) *yaml.Node {
+	for i := len(keys) - 1; i >= 0; i-- {
+		n = &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{{Kind: yaml.ScalarNode, Value: keys[i]}, n}}
+	}
+	return n
+}
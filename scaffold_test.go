@@ -0,0 +1,91 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+)
+
+func TestInitBase(t *testing.T) {
+	fs := memfs.New()
+	created, err := initBase(".", "sysdoc.yaml", []string{"*.sysdoc.md", "README.md"}, fs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(created) != 2 || created[1] != "README.md" {
+		t.Errorf("expected configuration and root definition, got %v", created)
+	}
+	cfg, err := NewConfig("sysdoc.yaml", fs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Renderer["default"].Templates.Global == "" {
+		t.Errorf("expected renderer 'default' to be configured")
+	}
+	if _, err := initBase(".", "sysdoc.yaml", []string{"README.md"}, fs); err == nil {
+		t.Errorf("expected an error if the configuration exists")
+	}
+}
+
+func TestAddDefinitions(t *testing.T) {
+	fs := memfs.New()
+	files := map[string]string{
+		"README.md":    "---\nname: Root # the root\n---\n# Root\n\nBody stays.\n",
+		"BE/README.md": "---\ninterfaces: {}\nchildren:\n  worker:\n    name: Worker\n---\n",
+	}
+	writeTestFiles(t, fs, files)
+	globs := []string{"README.md"}
+	load := func() *element {
+		return loadTestSystem(t, fs, ".", globs, config{})
+	}
+
+	steps := []func(sys *element) (string, error){
+		func(sys *element) (string, error) {
+			return sys.addElement("BE.DB", globs, mappingNode("type", "database"), fs)
+		},
+		func(sys *element) (string, error) {
+			return sys.addInterface("BE.DB.SQL", globs, mappingNode("protocol", "postgres"), fs)
+		},
+		func(sys *element) (string, error) {
+			return sys.addElement("BE.worker.job", globs, mappingNode("name", "Job"), fs)
+		},
+		func(sys *element) (string, error) {
+			return sys.addDependency("BE.worker.job.ToDB", "...DB.SQL", globs, mappingNode("depends_on", "...DB.SQL"), fs)
+		},
+		func(sys *element) (string, error) {
+			return sys.addInterface("API", globs, mappingNode(), fs)
+		},
+	}
+	for _, step := range steps {
+		if _, err := step(load()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := map[string]string{
+		"README.md":       "---\nname: Root # the root\ninterfaces:\n  API: {}\n---\n# Root\n\nBody stays.\n",
+		"BE/README.md":    "---\ninterfaces: {}\nchildren:\n  worker:\n    name: Worker\n    children:\n      job:\n        name: Job\n        dependencies:\n          ToDB:\n            depends_on: '...DB.SQL'\n---\n",
+		"BE/DB/README.md": "---\ntype: database\ninterfaces:\n  SQL:\n    protocol: postgres\n---\n",
+	}
+	for name, content := range expected {
+		data, err := util.ReadFile(fs, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != content {
+			t.Errorf("%s: expected:\n%s\ngot:\n%s", name, content, data)
+		}
+	}
+
+	sys := load()
+	if _, err := sys.addDependency("BE.ToX", "BE.X", globs, mappingNode("depends_on", "BE.X"), fs); err == nil {
+		t.Errorf("expected an error for a dependency on a missing interface")
+	}
+	if _, err := sys.addElement("BE.DB", globs, mappingNode(), fs); err == nil {
+		t.Errorf("expected an error for an existing element")
+	}
+	if _, err := sys.addInterface("BE.DB.SQL", globs, mappingNode(), fs); err == nil {
+		t.Errorf("expected an error for an existing interface")
+	}
+}
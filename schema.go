@@ -0,0 +1,184 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var fieldTypes = []string{"string", "number", "bool", "list", "url"}
+
+type schemaConfig struct {
+	// Tags lists all allowed tag keys, if empty any key is allowed
+	Tags map[string]tagSchema `yaml:"tags"`
+	// Fields lists all allowed custom fields, if empty any field is allowed
+	Fields   map[string]fieldSchema `yaml:"fields"`
+	Required []requiredRule         `yaml:"required"`
+}
+
+type tagSchema struct {
+	Values  []string `yaml:"values"`
+	Pattern string   `yaml:"pattern"`
+}
+
+type fieldSchema struct {
+	Type string `yaml:"type"`
+}
+
+// requiredRule lists tags and fields every element at a given depth or of a given type
+// must provide, rules without depth and type apply to all elements
+type requiredRule struct {
+	Depth  *int     `yaml:"depth"`
+	Type   string   `yaml:"type"`
+	Tags   []string `yaml:"tags"`
+	Fields []string `yaml:"fields"`
+}
+
+func (s schemaConfig) validate() error {
+	for key, t := range s.Tags {
+		if t.Pattern == "" {
+			continue
+		}
+		_, err := regexp.Compile(t.Pattern)
+		if err != nil {
+			return fmt.Errorf("Pattern of tag '%s' is not valid: %w", key, err)
+		}
+	}
+	for key, f := range s.Fields {
+		if !contains(fieldTypes, f.Type) {
+			return fmt.Errorf("Type '%s' of field '%s' is not valid, please choose one of %v", f.Type, key, fieldTypes)
+		}
+	}
+	return nil
+}
+
+func (s schemaConfig) validateTags(tags map[string]string) []error {
+	errs := []error{}
+	if len(s.Tags) == 0 {
+		return errs
+	}
+	for _, key := range sortedKeys(tags) {
+		value := tags[key]
+		t, ok := s.Tags[key]
+		if !ok {
+			errs = append(errs, fmt.Errorf("Tag '%s' is not allowed, please choose one of %v", key, sortedKeys(s.Tags)))
+			continue
+		}
+		if len(t.Values) > 0 && !contains(t.Values, value) {
+			errs = append(errs, fmt.Errorf("Value '%s' of tag '%s' is not allowed, please choose one of %v", value, key, t.Values))
+		}
+		if t.Pattern != "" {
+			if match, _ := regexp.MatchString(t.Pattern, value); !match {
+				errs = append(errs, fmt.Errorf("Value '%s' of tag '%s' does not match '%s'", value, key, t.Pattern))
+			}
+		}
+	}
+	return errs
+}
+
+func (s schemaConfig) validateFields(fields map[string]interface{}) []error {
+	errs := []error{}
+	if len(s.Fields) == 0 {
+		return errs
+	}
+	for _, key := range sortedKeys(fields) {
+		f, ok := s.Fields[key]
+		if !ok {
+			errs = append(errs, fmt.Errorf("Field '%s' is not allowed, please choose one of %v", key, sortedKeys(s.Fields)))
+			continue
+		}
+		if !f.matches(fields[key]) {
+			errs = append(errs, fmt.Errorf("Value '%v' of field '%s' is not of type %s", fields[key], key, f.Type))
+		}
+	}
+	return errs
+}
+
+func (f fieldSchema) matches(value interface{}) bool {
+	switch f.Type {
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		switch value.(type) {
+		case int, int64, uint64, float64:
+			return true
+		}
+		return false
+	case "bool":
+		_, ok := value.(bool)
+		return ok
+	case "list":
+		_, ok := value.([]interface{})
+		return ok
+	case "url":
+		s, ok := value.(string)
+		if !ok {
+			return false
+		}
+		u, err := url.ParseRequestURI(s)
+		return err == nil && u.Scheme != "" && u.Host != ""
+	}
+	return false
+}
+
+// validateConfiguration checks all tags and fields of an element configuration
+func (s schemaConfig) validateConfiguration(ec elementConfiguration) error {
+	errs := s.validateTags(ec.Tags)
+	errs = append(errs, s.validateFields(ec.Fields)...)
+	for _, key := range sortedKeys(ec.Interfaces) {
+		for _, err := range s.validateTags(ec.Interfaces[key].Tags) {
+			errs = append(errs, fmt.Errorf("Interface '%s': %w", key, err))
+		}
+	}
+	for _, key := range sortedKeys(ec.Dependencies) {
+		for _, err := range s.validateTags(ec.Dependencies[key].Tags) {
+			errs = append(errs, fmt.Errorf("Dependency '%s': %w", key, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// checkRequired returns an error for every element below root which lacks a tag or field
+// required by the schema, inherited tags satisfy the requirements
+func (s schemaConfig) checkRequired(root *element) []error {
+	errs := []error{}
+	for _, e := range root.getElements() {
+		depth := len(e.position()) - 1
+		for _, r := range s.Required {
+			if r.Depth != nil && *r.Depth != depth {
+				continue
+			}
+			if r.Type != "" && r.Type != e.elementType {
+				continue
+			}
+			missing := []string{}
+			for _, key := range r.Tags {
+				if _, ok := e.tags[key]; !ok {
+					missing = append(missing, fmt.Sprintf("tag '%s'", key))
+				}
+			}
+			for _, key := range r.Fields {
+				if _, ok := e.fields[key]; !ok {
+					missing = append(missing, fmt.Sprintf("field '%s'", key))
+				}
+			}
+			if len(missing) > 0 {
+				errs = append(errs, fmt.Errorf("Element '%s' is missing required %s", strings.Join(e.position(), "."), strings.Join(missing, ", ")))
+			}
+		}
+	}
+	return errs
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	out := make([]string, 0, len(m))
+	for key := range m {
+		out = append(out, key)
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/util"
+)
+
+func testSchema() schemaConfig {
+	one := 1
+	return schemaConfig{
+		Tags: map[string]tagSchema{
+			"external": {Values: []string{"true", "false"}},
+			"link":     {Pattern: "^https?://"},
+			"type":     {},
+		},
+		Fields: map[string]fieldSchema{
+			"cost":  {Type: "number"},
+			"hosts": {Type: "list"},
+			"docs":  {Type: "url"},
+		},
+		Required: []requiredRule{
+			{Depth: &one, Tags: []string{"external"}},
+			{Type: "person", Fields: []string{"docs"}},
+		},
+	}
+}
+
+func TestValidateConfiguration(t *testing.T) {
+	s := testSchema()
+	tests := []struct {
+		data   string
+		errors []string
+	}{
+		{
+			data: "---\ntags:\n  external: \"true\"\n  link: https://example.com\nfields:\n  cost: 12.5\n  hosts: [a, b]\n  docs: https://example.com/docs\n---\n",
+		},
+		{
+			data:   "---\ntags:\n  extrenal: \"true\"\n---\n",
+			errors: []string{"Tag 'extrenal' is not allowed"},
+		},
+		{
+			data:   "---\ntags:\n  external: \"yes\"\n  link: ftp://example.com\n---\n",
+			errors: []string{"Value 'yes' of tag 'external' is not allowed", "Value 'ftp://example.com' of tag 'link' does not match"},
+		},
+		{
+			data:   "---\nfields:\n  cost: expensive\n  docs: not a url\n  owner: me\n---\n",
+			errors: []string{"field 'cost' is not of type number", "field 'docs' is not of type url", "Field 'owner' is not allowed"},
+		},
+		{
+			data:   "---\ninterfaces:\n  API:\n    tags:\n      external: maybe\n---\n",
+			errors: []string{"Interface 'API': Value 'maybe' of tag 'external'"},
+		},
+	}
+	for _, tc := range tests {
+		fs := memfs.New()
+		err := util.WriteFile(fs, "README.md", []byte(tc.data), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = newElementConfigurationFromFile("README.md", s, fs)
+		if len(tc.errors) == 0 {
+			if err != nil {
+				t.Errorf("expected no error, got: %s", err)
+			}
+			continue
+		}
+		if err == nil {
+			t.Errorf("expected errors %v, got none", tc.errors)
+			continue
+		}
+		for _, e := range tc.errors {
+			if !strings.Contains(err.Error(), e) {
+				t.Errorf("expected error containing '%s', got: %s", e, err)
+			}
+		}
+	}
+}
+
+func TestCheckRequired(t *testing.T) {
+	root := newElement("root", elementConfiguration{})
+	a := newElement("A", elementConfiguration{Tags: map[string]string{"external": "false"}})
+	b := newElement("B", elementConfiguration{})
+	u := newElement("U", elementConfiguration{Type: "person"})
+	for _, tc := range []struct {
+		e   *element
+		pos []string
+	}{
+		{e: a, pos: []string{"A"}},
+		{e: b, pos: []string{"B"}},
+		{e: u, pos: []string{"A", "U"}},
+	} {
+		err := root.appendAt(tc.e, tc.pos)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	errs := testSchema().checkRequired(root)
+	want := []string{
+		"Element 'root.A.U' is missing required field 'docs'",
+		"Element 'root.B' is missing required tag 'external'",
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("expected %d errors, got %v", len(want), errs)
+	}
+	for i := range want {
+		if errs[i].Error() != want[i] {
+			t.Errorf("expected '%s', got '%s'", want[i], errs[i])
+		}
+	}
+}
+
+func TestInvalidDefinitions(t *testing.T) {
+	fs := memfs.New()
+	files := map[string]string{
+		"README.md":   "---\nname: Root\n---\n",
+		"A/README.md": "---\ntags:\n  extrenal: \"true\"\n---\n",
+		"B/README.md": "---\ntags:\n  link: ftp://example.com\n---\n",
+		"C/README.md": "---\nname: Valid\n---\n",
+	}
+	writeTestFiles(t, fs, files)
+	_, err := newElementFromPersistence(".", []string{"README.md"}, config{Schema: testSchema()}, fs)
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok || len(joined.Unwrap()) != 2 {
+		t.Fatalf("expected an error for each invalid definition, got: %v", err)
+	}
+	for _, file := range []string{"A/README.md", "B/README.md"} {
+		if !strings.Contains(err.Error(), file) {
+			t.Errorf("expected an error for '%s', got: %s", file, err)
+		}
+	}
+}
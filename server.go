@@ -26,14 +26,15 @@ type server struct {
 	indexTemplate  *template.Template
 	listener       string
 	base           string
-	glob           string
+	glob           []string
+	configfile     string
 	cache          cache.Cache
 	persistence    persistence.Persistence
 	rendererConfig renderConfig
 	renderer       Renderer
 }
 
-func NewServer(listener, base, glob, cacheTimeout string, p persistence.Persistence, r Renderer) (*server, error) {
+func NewServer(listener, base string, glob []string, configfile, cacheTimeout string, p persistence.Persistence, r Renderer) (*server, error) {
 	durr, err := time.ParseDuration(cacheTimeout)
 	if err != nil {
 		return nil, err
@@ -43,6 +44,7 @@ func NewServer(listener, base, glob, cacheTimeout string, p persistence.Persiste
 		listener:    listener,
 		base:        base,
 		glob:        glob,
+		configfile:  configfile,
 		cache:       *cache.New(durr),
 		persistence: p,
 		renderer:    r,
@@ -74,6 +76,7 @@ func (s *server) Run() error {
 	http.Handle("/static/", fs)
 	http.HandleFunc("/svg/", s.HandleSVG)
 	http.HandleFunc("/branches.json", s.HandleBranches)
+	http.HandleFunc("/api/query", s.HandleQuery)
 	http.HandleFunc("/index.html", s.HandleIndex)
 	http.HandleFunc("/", s.HandleIndex)
 
@@ -126,8 +129,20 @@ func (s *server) HandleSVG(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	cfg, err := NewConfig(s.configfile, s.persistence.Filesystem())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+
 	// build system
-	sys, errs := NewSystem(s.base, s.glob, focus, s.persistence)
+	f := filter{
+		Focus: focus,
+		Owner: r.URL.Query().Get("owner"),
+		View:  r.URL.Query().Get("view"),
+	}
+	sys, errs := NewSystem(s.base, s.glob, f, cfg, s.persistence)
 	if len(errs) > 0 {
 		w.WriteHeader(http.StatusInternalServerError)
 		out := ""
@@ -153,3 +168,60 @@ func (s *server) HandleSVG(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("content-Type", "image/svg+xml")
 	_, _ = w.Write([]byte(tidy))
 }
+
+func (s *server) HandleQuery(w http.ResponseWriter, r *http.Request) {
+	q, err := newQuery(r.URL.Query().Get("q"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+
+	branch := r.URL.Query().Get("branch")
+	if branch != "" {
+		err := s.persistence.CheckoutBranch(branch)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(err.Error()))
+			return
+		}
+	}
+
+	cfg, err := NewConfig(s.configfile, s.persistence.Filesystem())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte(err.Error()))
+		return
+	}
+
+	// build system
+	sys, errs := NewSystem(s.base, s.glob, filter{}, cfg, s.persistence)
+	if len(errs) > 0 {
+		w.WriteHeader(http.StatusInternalServerError)
+		out := ""
+		for _, err := range errs {
+			out += fmt.Sprintf("%s\n", err.Error())
+		}
+		_, _ = w.Write([]byte(out))
+		return
+	}
+
+	var out interface{}
+	elems := q.Select(sys)
+	if r.URL.Query().Get("records") == "true" {
+		records := []queryRecord{}
+		for _, e := range elems {
+			records = append(records, newQueryRecord(e))
+		}
+		out = records
+	} else {
+		ids := []string{}
+		for _, e := range elems {
+			ids = append(ids, e.getID("."))
+		}
+		out = ids
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(out)
+}
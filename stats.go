@@ -0,0 +1,302 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+)
+
+type elementMetrics struct {
+	ID                   string  `json:"id"`
+	Depth                int     `json:"depth"`
+	FanIn                int     `json:"fan_in"`
+	FanOut               int     `json:"fan_out"`
+	TransitiveFanIn      int     `json:"transitive_fan_in"`
+	TransitiveFanOut     int     `json:"transitive_fan_out"`
+	Criticality          float64 `json:"criticality"`
+	SinglePointOfFailure bool    `json:"single_point_of_failure"`
+	Orphaned             bool    `json:"orphaned"`
+}
+
+type interfMetrics struct {
+	ID              string  `json:"id"`
+	FanIn           int     `json:"fan_in"`
+	TransitiveFanIn int     `json:"transitive_fan_in"`
+	Criticality     float64 `json:"criticality"`
+	Unused          bool    `json:"unused"`
+}
+
+// elementGraph is the directed graph of elements where an edge from a to b
+// means that a depends on at least one interface of b
+type elementGraph struct {
+	nodes []*element
+	out   map[*element]map[*element]bool
+	in    map[*element]map[*element]bool
+}
+
+func newElementGraph(root *element) elementGraph {
+	g := elementGraph{
+		nodes: root.getElements(),
+		out:   map[*element]map[*element]bool{},
+		in:    map[*element]map[*element]bool{},
+	}
+	for _, n := range g.nodes {
+		g.out[n] = map[*element]bool{}
+		g.in[n] = map[*element]bool{}
+	}
+	for _, dep := range root.getDependencies() {
+		if dep.provider() == nil {
+			continue
+		}
+		from, to := dep.belongsTo, dep.provider()
+		if from == to {
+			continue
+		}
+		g.out[from][to] = true
+		g.in[to][from] = true
+	}
+	return g
+}
+
+// reach returns the distance of every element reachable from start following
+// the given edges, start itself is not part of the result
+func (g elementGraph) reach(start *element, edges map[*element]map[*element]bool) map[*element]int {
+	dist := map[*element]int{start: 0}
+	queue := []*element{start}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for next := range edges[current] {
+			if _, seen := dist[next]; seen {
+				continue
+			}
+			dist[next] = dist[current] + 1
+			queue = append(queue, next)
+		}
+	}
+	delete(dist, start)
+	return dist
+}
+
+// articulationPoints returns all elements which disconnect the graph (ignoring the
+// direction of the dependencies) when removed
+func (g elementGraph) articulationPoints() map[*element]bool {
+	adjacent := map[*element][]*element{}
+	for _, n := range g.nodes {
+		for m := range g.out[n] {
+			adjacent[n] = append(adjacent[n], m)
+		}
+		for m := range g.in[n] {
+			if !g.out[n][m] {
+				adjacent[n] = append(adjacent[n], m)
+			}
+		}
+	}
+
+	out := map[*element]bool{}
+	discovery := map[*element]int{}
+	low := map[*element]int{}
+	time := 0
+	var visit func(n, parent *element)
+	visit = func(n, parent *element) {
+		time++
+		discovery[n] = time
+		low[n] = time
+		children := 0
+		for _, m := range adjacent[n] {
+			if _, seen := discovery[m]; !seen {
+				children++
+				visit(m, n)
+				if low[m] < low[n] {
+					low[n] = low[m]
+				}
+				if parent != nil && low[m] >= discovery[n] {
+					out[n] = true
+				}
+			} else if m != parent && discovery[m] < low[n] {
+				low[n] = discovery[m]
+			}
+		}
+		if parent == nil && children > 1 {
+			out[n] = true
+		}
+	}
+	for _, n := range g.nodes {
+		if _, seen := discovery[n]; !seen {
+			visit(n, nil)
+		}
+	}
+	return out
+}
+
+// calculateMetrics computes the graph metrics of all elements and interfaces below
+// root. It needs to be called on a tree with resolved dependencies.
+func (root *element) calculateMetrics() {
+	g := newElementGraph(root)
+	spof := g.articulationPoints()
+
+	others := float64(len(g.nodes) - 1)
+	criticality := func(n int) float64 {
+		if others < 1 {
+			return 0
+		}
+		return float64(n) / others
+	}
+
+	transitiveIn := map[*element]map[*element]int{}
+	for _, n := range g.nodes {
+		transitiveIn[n] = g.reach(n, g.in)
+		transitiveOut := g.reach(n, g.out)
+		depth := 0
+		for _, d := range transitiveOut {
+			if d > depth {
+				depth = d
+			}
+		}
+		n.metrics = &elementMetrics{
+			ID:                   n.getID("."),
+			Depth:                depth,
+			FanIn:                len(g.in[n]),
+			FanOut:               len(g.out[n]),
+			TransitiveFanIn:      len(transitiveIn[n]),
+			TransitiveFanOut:     len(transitiveOut),
+			Criticality:          criticality(len(transitiveIn[n])),
+			SinglePointOfFailure: spof[n],
+		}
+	}
+
+	// an element is orphaned if neither the element itself nor any of its
+	// children is part of a dependency
+	var orphaned func(e *element) bool
+	orphaned = func(e *element) bool {
+		out := len(g.in[e]) == 0 && len(g.out[e]) == 0
+		for _, child := range e.children {
+			if !orphaned(child) {
+				out = false
+			}
+		}
+		e.metrics.Orphaned = out
+		return out
+	}
+	orphaned(root)
+
+	consumers := map[*interf]map[*element]bool{}
+	for _, dep := range root.getDependencies() {
+		for _, i := range dep.providedInterfaces() {
+			if consumers[i] == nil {
+				consumers[i] = map[*element]bool{}
+			}
+			consumers[i][dep.belongsTo] = true
+		}
+	}
+	for _, i := range root.getInterfaces() {
+		transitive := map[*element]bool{}
+		for c := range consumers[i] {
+			transitive[c] = true
+			for t := range transitiveIn[c] {
+				transitive[t] = true
+			}
+		}
+		delete(transitive, i.belongsTo)
+		i.metrics = &interfMetrics{
+			ID:              i.getID("."),
+			FanIn:           len(consumers[i]),
+			TransitiveFanIn: len(transitive),
+			Criticality:     criticality(len(transitive)),
+			Unused:          len(consumers[i]) == 0,
+		}
+	}
+}
+
+type statsReport struct {
+	Elements   []elementMetrics `json:"elements"`
+	Interfaces []interfMetrics  `json:"interfaces"`
+}
+
+func newStatsReport(root *element) statsReport {
+	r := statsReport{
+		Elements:   []elementMetrics{},
+		Interfaces: []interfMetrics{},
+	}
+	for _, e := range root.getElements() {
+		if e.parent == nil || e.metrics == nil {
+			continue
+		}
+		r.Elements = append(r.Elements, *e.metrics)
+	}
+	for _, i := range root.getInterfaces() {
+		if i.metrics == nil {
+			continue
+		}
+		r.Interfaces = append(r.Interfaces, *i.metrics)
+	}
+	sort.Slice(r.Elements, func(a, b int) bool { return r.Elements[a].ID < r.Elements[b].ID })
+	sort.Slice(r.Interfaces, func(a, b int) bool { return r.Interfaces[a].ID < r.Interfaces[b].ID })
+	return r
+}
+
+func (r statsReport) rows(interfaces bool) [][]string {
+	if interfaces {
+		out := [][]string{{"ID", "FAN-IN", "TRANSITIVE-FAN-IN", "CRITICALITY", "UNUSED"}}
+		for _, m := range r.Interfaces {
+			out = append(out, []string{
+				m.ID,
+				strconv.Itoa(m.FanIn),
+				strconv.Itoa(m.TransitiveFanIn),
+				strconv.FormatFloat(m.Criticality, 'f', 2, 64),
+				strconv.FormatBool(m.Unused),
+			})
+		}
+		return out
+	}
+	out := [][]string{{"ID", "DEPTH", "FAN-IN", "FAN-OUT", "TRANSITIVE-FAN-IN", "TRANSITIVE-FAN-OUT", "CRITICALITY", "SPOF", "ORPHANED"}}
+	for _, m := range r.Elements {
+		out = append(out, []string{
+			m.ID,
+			strconv.Itoa(m.Depth),
+			strconv.Itoa(m.FanIn),
+			strconv.Itoa(m.FanOut),
+			strconv.Itoa(m.TransitiveFanIn),
+			strconv.Itoa(m.TransitiveFanOut),
+			strconv.FormatFloat(m.Criticality, 'f', 2, 64),
+			strconv.FormatBool(m.SinglePointOfFailure),
+			strconv.FormatBool(m.Orphaned),
+		})
+	}
+	return out
+}
+
+// Write prints the report in the given format, table and csv output contain either the
+// element or the interface metrics whereas json always contains both
+func (r statsReport) Write(w io.Writer, format string, interfaces bool) error {
+	switch format {
+	case "table":
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		for _, row := range r.rows(interfaces) {
+			for i, col := range row {
+				if i > 0 {
+					fmt.Fprint(tw, "\t")
+				}
+				fmt.Fprint(tw, col)
+			}
+			fmt.Fprint(tw, "\n")
+		}
+		return tw.Flush()
+	case "csv":
+		cw := csv.NewWriter(w)
+		err := cw.WriteAll(r.rows(interfaces))
+		if err != nil {
+			return err
+		}
+		return cw.Error()
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	}
+	return fmt.Errorf("Output format '%s' is not supported, please choose one of [table csv json]", format)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"sysdoc/internal/persistence"
+	"testing"
+)
+
+func TestCalculateMetrics(t *testing.T) {
+	p, err := persistence.NewLocal("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sys, errs := NewSystem(".", []string{"README.md"}, filter{}, config{}, p)
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	report := newStatsReport(sys)
+	elements := map[string]elementMetrics{}
+	for _, m := range report.Elements {
+		elements[m.ID] = m
+	}
+
+	tests := []elementMetrics{
+		{ID: "BE"},
+		{ID: "BE.DB", FanIn: 1, TransitiveFanIn: 2, Criticality: 0.4},
+		{ID: "BE.SVC", Depth: 1, FanIn: 1, FanOut: 1, TransitiveFanIn: 1, TransitiveFanOut: 1, Criticality: 0.2, SinglePointOfFailure: true},
+		{ID: "FE"},
+		{ID: "FE.WEB", Depth: 2, FanOut: 1, TransitiveFanOut: 2},
+	}
+	if len(elements) != len(tests) {
+		t.Fatalf("expected %d elements, got %d", len(tests), len(elements))
+	}
+	for _, tc := range tests {
+		got, ok := elements[tc.ID]
+		if !ok {
+			t.Errorf("expected metrics for element '%s'", tc.ID)
+			continue
+		}
+		if got != tc {
+			t.Errorf("expected: %+v, got: %+v", tc, got)
+		}
+	}
+
+	interfaces := map[string]interfMetrics{}
+	for _, m := range report.Interfaces {
+		interfaces[m.ID] = m
+	}
+	if got := interfaces["BE.DB.SQL"]; got.FanIn != 1 || got.TransitiveFanIn != 2 || got.Unused {
+		t.Errorf("unexpected metrics for 'BE.DB.SQL': %+v", got)
+	}
+}
+
+func TestOrphanedAndUnused(t *testing.T) {
+	root := newElement("root", elementConfiguration{})
+	a := newElement("A", elementConfiguration{
+		Interfaces: map[string]interfConfiguration{"IF": {}},
+	})
+	b := newElement("B", elementConfiguration{})
+	for _, e := range []*element{a, b} {
+		err := root.appendAt(e, []string{e.fragment})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	root.calculateMetrics()
+
+	if !a.metrics.Orphaned || !b.metrics.Orphaned {
+		t.Errorf("expected elements without dependencies to be orphaned")
+	}
+	if !a.interfaces[0].metrics.Unused {
+		t.Errorf("expected interface without consumers to be unused")
+	}
+}
@@ -0,0 +1,728 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-billy/v5/util"
+	"gopkg.in/yaml.v3"
+)
+
+// Values without a counterpart in Structurizr are kept as properties with these keys, so
+// that they survive an export followed by an import
+const (
+	structurizrType        = "sysdoc.type"
+	structurizrTeam        = "sysdoc.team"
+	structurizrTag         = "sysdoc.tag."
+	structurizrInterface   = "sysdoc.interface"
+	structurizrKind        = "sysdoc.kind"
+	structurizrCriticality = "sysdoc.criticality"
+	structurizrDescription = "sysdoc.description"
+)
+
+// structurizrKinds are the element keywords of the DSL by the depth of the element
+var structurizrKinds = []string{"softwareSystem", "container", "component"}
+
+var structurizrInvalid = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// dslUnsupported are the directives which cannot be imported as they refer to other files
+// or to elements defined elsewhere
+var dslUnsupported = []string{"!include", "!ref", "!extend", "!element", "!relationship", "!script", "!plugin"}
+
+// writeStructurizr writes the elements below root as Structurizr DSL workspace with a system
+// landscape view and a context, container and component view per element where applicable.
+// Interfaces are kept as properties of their element and of the relationships. Deeper
+// elements need to be collapsed into their component by a depth filter beforehand.
+func writeStructurizr(w io.Writer, sys *element) error {
+	ids := map[*element]string{}
+	for _, e := range sys.getElements() {
+		if len(e.position())-1 > len(structurizrKinds) {
+			return fmt.Errorf("Element '%s' is nested deeper than %d levels, please limit the depth", e.getID("."), len(structurizrKinds))
+		}
+	}
+	for _, e := range sys.getElements() {
+		if e.parent == nil {
+			continue
+		}
+		ids[e] = structurizrInvalid.ReplaceAllString(e.fragment, "_")
+		if e.parent.parent != nil {
+			ids[e] = ids[e.parent] + "." + ids[e]
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "workspace %s {\n    !identifiers hierarchical\n\n    model {\n", dslString(orFragment(sys)))
+	for _, e := range sys.children {
+		writeStructurizrElement(&b, e, ids, 2)
+	}
+	seen := map[string]bool{}
+	for _, d := range sys.getDependencies() {
+		provider := d.provider()
+		if d.belongsTo.parent == nil || provider == nil || provider.parent == nil {
+			continue
+		}
+		// relationships between parents and their children are not allowed
+		if d.belongsTo.hasParent(provider) || provider.hasParent(d.belongsTo) {
+			continue
+		}
+		description := d.description
+		if description == "" {
+			description = "Uses"
+		}
+		props := map[string]string{structurizrKind: d.kind, structurizrCriticality: d.criticality}
+		if d.dependsOn != nil {
+			props[structurizrInterface] = d.dependsOn.fragment
+		}
+		// relationships with the same description between two elements are not allowed
+		key := func() string { return ids[d.belongsTo] + " " + ids[provider] + " " + description }
+		if seen[key()] && d.dependsOn != nil {
+			description = fmt.Sprintf("%s (%s)", description, d.dependsOn.fragment)
+		}
+		for base, n := description, 2; seen[key()]; n++ {
+			description = fmt.Sprintf("%s (%d)", base, n)
+		}
+		seen[key()] = true
+		// the default and the numbering of descriptions are not part of the dependency
+		if description != d.description {
+			props[structurizrDescription] = d.description
+		}
+		for k, v := range d.tags {
+			props[structurizrTag+k] = v
+		}
+		fmt.Fprintf(&b, "\n        %s -> %s %s", ids[d.belongsTo], ids[provider], dslString(description))
+		if d.protocol != "" {
+			fmt.Fprintf(&b, " %s", dslString(d.protocol))
+		}
+		if properties := structurizrProperties(props, 3); properties != "" {
+			fmt.Fprintf(&b, " {\n%s        }", properties)
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("    }\n\n    views {\n        systemLandscape {\n            include *\n            autolayout\n        }\n")
+	for _, e := range sys.getElements() {
+		depth := len(e.position()) - 1
+		if depth == 0 || e.elementType == "person" && depth == 1 && len(e.children) == 0 {
+			continue
+		}
+		views := []string{}
+		switch {
+		case depth == 1:
+			views = append(views, "systemContext")
+			if len(e.children) > 0 {
+				views = append(views, "container")
+			}
+		case depth == 2 && len(e.children) > 0:
+			views = append(views, "component")
+		}
+		for _, v := range views {
+			fmt.Fprintf(&b, "        %s %s {\n            include *\n            autolayout\n        }\n", v, ids[e])
+		}
+	}
+	b.WriteString("    }\n}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeStructurizrElement(b *strings.Builder, e *element, ids map[*element]string, level int) {
+	indent := strings.Repeat("    ", level)
+	depth := len(e.position()) - 2
+	kind := structurizrKinds[depth]
+	if depth == 0 && e.elementType == "person" && len(e.children) == 0 {
+		kind = "person"
+	}
+	fragments := strings.Split(ids[e], ".")
+	fmt.Fprintf(b, "%s%s = %s %s {\n", indent, fragments[len(fragments)-1], kind, dslString(orFragment(e)))
+	if description := docDescription(e.doc); description != "" {
+		fmt.Fprintf(b, "%s    description %s\n", indent, dslString(description))
+	}
+	props := map[string]string{structurizrType: e.elementType, structurizrTeam: e.owner.Team}
+	for k, v := range e.declaredTags {
+		if k == "technology" && kind != "person" && kind != "softwareSystem" {
+			fmt.Fprintf(b, "%s    technology %s\n", indent, dslString(v))
+			continue
+		}
+		props[structurizrTag+k] = v
+	}
+	for _, i := range e.interfaces {
+		props[structurizrInterface+"."+i.fragment] = i.description
+	}
+	b.WriteString(structurizrProperties(props, level+1))
+	for _, c := range e.children {
+		writeStructurizrElement(b, c, ids, level+1)
+	}
+	fmt.Fprintf(b, "%s}\n", indent)
+}
+
+// structurizrProperties returns the properties block of the non empty properties
+func structurizrProperties(props map[string]string, level int) string {
+	indent := strings.Repeat("    ", level)
+	lines := []string{}
+	for _, k := range sortedKeys(props) {
+		// interfaces are kept even without a description, as are empty descriptions
+		if props[k] == "" && !strings.HasPrefix(k, structurizrInterface+".") && k != structurizrDescription {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s    %s %s\n", indent, dslString(k), dslString(props[k])))
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%sproperties {\n%s%s}\n", indent, strings.Join(lines, ""), indent)
+}
+
+// docDescription returns the first paragraph of text of the documentation, headings, code
+// blocks, images and HTML are skipped
+func docDescription(doc []byte) string {
+	lines := []string{}
+	fenced := false
+	for _, line := range strings.Split(string(doc), "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "```") {
+			fenced = !fenced
+			continue
+		}
+		switch {
+		case fenced:
+			continue
+		case line == "" && len(lines) > 0:
+			return strings.Join(lines, " ")
+		case line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "<") || strings.HasPrefix(line, "!["):
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, " ")
+}
+
+func orFragment(e *element) string {
+	if e.name != "" {
+		return e.name
+	}
+	return e.fragment
+}
+
+func dslString(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	return `"` + strings.ReplaceAll(strings.ReplaceAll(s, `\`, `\\`), `"`, `\"`) + `"`
+}
+
+// IMPORT
+
+type structurizrElement struct {
+	id          string
+	kind        string
+	name        string
+	description string
+	technology  string
+	tags        []string
+	properties  map[string]string
+	children    []*structurizrElement
+	parent      *structurizrElement
+	fragment    string
+}
+
+type structurizrRelationship struct {
+	source      string
+	destination string
+	description string
+	technology  string
+	properties  map[string]string
+	from        *structurizrElement
+	line        int
+}
+
+type structurizrWorkspace struct {
+	name          string
+	elements      []*structurizrElement
+	relationships []*structurizrRelationship
+}
+
+// dslStatement is a line of the DSL split into tokens, open is set if it opens a block
+type dslStatement struct {
+	line   int
+	tokens []string
+	open   bool
+}
+
+// parseStructurizr parses the model of a Structurizr DSL workspace, views, styles and
+// deployment environments are skipped
+func parseStructurizr(data []byte) (structurizrWorkspace, error) {
+	ws := structurizrWorkspace{}
+	statements, err := dslStatements(string(data))
+	if err != nil {
+		return ws, err
+	}
+	p := &dslParser{statements: statements}
+	s, ok := p.next()
+	if !ok || !strings.EqualFold(s.tokens[0], "workspace") || !s.open {
+		return ws, fmt.Errorf("Workspace is missing")
+	}
+	if len(s.tokens) > 1 && s.tokens[1] != "extends" {
+		ws.name = s.tokens[1]
+	}
+	for {
+		s, ok := p.next()
+		if !ok {
+			return ws, fmt.Errorf("Workspace is not terminated")
+		}
+		switch {
+		case s.tokens[0] == "}":
+			return ws, p.end()
+		case strings.EqualFold(s.tokens[0], "model") && s.open:
+			err = p.model(&ws, nil)
+		case strings.EqualFold(s.tokens[0], "name") && len(s.tokens) > 1:
+			ws.name = s.tokens[1]
+		case contains(dslUnsupported, strings.ToLower(s.tokens[0])):
+			err = fmt.Errorf("Line %d: '%s' is not supported", s.line, s.tokens[0])
+		case s.open:
+			err = p.skip()
+		}
+		if err != nil {
+			return ws, err
+		}
+	}
+}
+
+type dslParser struct {
+	statements []dslStatement
+	pos        int
+}
+
+func (p *dslParser) next() (dslStatement, bool) {
+	if p.pos >= len(p.statements) {
+		return dslStatement{}, false
+	}
+	p.pos++
+	return p.statements[p.pos-1], true
+}
+
+func (p *dslParser) end() error {
+	if s, ok := p.next(); ok {
+		return fmt.Errorf("Line %d: unexpected content after the workspace", s.line)
+	}
+	return nil
+}
+
+// skip consumes the rest of a block
+func (p *dslParser) skip() error {
+	depth := 1
+	for depth > 0 {
+		s, ok := p.next()
+		if !ok {
+			return fmt.Errorf("Block is not terminated")
+		}
+		if s.open {
+			depth++
+		}
+		if s.tokens[0] == "}" {
+			depth--
+		}
+	}
+	return nil
+}
+
+// model parses the content of the model or an element block, parent is nil for the model
+func (p *dslParser) model(ws *structurizrWorkspace, parent *structurizrElement) error {
+	for {
+		s, ok := p.next()
+		if !ok {
+			return fmt.Errorf("Block is not terminated")
+		}
+		tokens := s.tokens
+		if tokens[0] == "}" {
+			return nil
+		}
+		id := ""
+		if len(tokens) > 2 && tokens[1] == "=" {
+			id, tokens = tokens[0], tokens[2:]
+		}
+		keyword := strings.ToLower(tokens[0])
+		var err error
+		switch {
+		case tokens[0] == "->" || len(tokens) > 2 && tokens[1] == "->":
+			r := &structurizrRelationship{from: parent, line: s.line, properties: map[string]string{}}
+			if tokens[0] == "->" {
+				if parent == nil {
+					return fmt.Errorf("Line %d: relationship without source", s.line)
+				}
+				tokens = tokens[1:]
+			} else {
+				r.source, tokens = tokens[0], tokens[2:]
+			}
+			r.destination = tokens[0]
+			r.description, r.technology = dslArg(tokens, 1), dslArg(tokens, 2)
+			ws.relationships = append(ws.relationships, r)
+			if s.open {
+				err = p.relationship(r)
+			}
+		case contains([]string{"person", "softwaresystem", "container", "component"}, keyword):
+			e := &structurizrElement{id: id, kind: keyword, name: dslArg(tokens, 1), description: dslArg(tokens, 2), parent: parent, properties: map[string]string{}}
+			tagsArg := 3
+			if keyword == "container" || keyword == "component" {
+				e.technology, tagsArg = dslArg(tokens, 3), 4
+			}
+			e.tags = dslTags(dslArg(tokens, tagsArg))
+			if parent == nil {
+				ws.elements = append(ws.elements, e)
+			} else {
+				parent.children = append(parent.children, e)
+			}
+			if s.open {
+				err = p.model(ws, e)
+			}
+		case keyword == "group" && s.open:
+			err = p.model(ws, parent)
+		case keyword == "enterprise" && s.open && parent == nil:
+			err = p.model(ws, nil)
+		case parent != nil && keyword == "description":
+			parent.description = dslArg(tokens, 1)
+		case parent != nil && keyword == "technology":
+			parent.technology = dslArg(tokens, 1)
+		case parent != nil && keyword == "tags":
+			for _, t := range tokens[1:] {
+				parent.tags = append(parent.tags, dslTags(t)...)
+			}
+		case parent != nil && keyword == "properties" && s.open:
+			err = p.properties(parent.properties)
+		case contains(dslUnsupported, keyword):
+			err = fmt.Errorf("Line %d: '%s' is not supported", s.line, tokens[0])
+		case s.open:
+			err = p.skip()
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// properties parses a properties block
+func (p *dslParser) properties(into map[string]string) error {
+	for {
+		s, ok := p.next()
+		if !ok {
+			return fmt.Errorf("Block is not terminated")
+		}
+		switch {
+		case s.tokens[0] == "}":
+			return nil
+		case s.open:
+			err := p.skip()
+			if err != nil {
+				return err
+			}
+		case len(s.tokens) > 1:
+			into[s.tokens[0]] = s.tokens[1]
+		}
+	}
+}
+
+// relationship parses the block of a relationship, of which only the properties are kept
+func (p *dslParser) relationship(r *structurizrRelationship) error {
+	for {
+		s, ok := p.next()
+		if !ok {
+			return fmt.Errorf("Block is not terminated")
+		}
+		var err error
+		switch {
+		case s.tokens[0] == "}":
+			return nil
+		case strings.EqualFold(s.tokens[0], "properties") && s.open:
+			err = p.properties(r.properties)
+		case strings.EqualFold(s.tokens[0], "description"):
+			r.description = dslArg(s.tokens, 1)
+		case strings.EqualFold(s.tokens[0], "technology"):
+			r.technology = dslArg(s.tokens, 1)
+		case s.open:
+			err = p.skip()
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func dslArg(tokens []string, i int) string {
+	if i < len(tokens) {
+		return tokens[i]
+	}
+	return ""
+}
+
+func dslTags(s string) []string {
+	out := []string{}
+	for _, t := range strings.Split(s, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// dslStatements splits the DSL into statements of tokens, comments are removed and a
+// trailing '{' marks a statement opening a block
+func dslStatements(data string) ([]dslStatement, error) {
+	out := []dslStatement{}
+	comment := false
+	for n, line := range strings.Split(data, "\n") {
+		s := dslStatement{line: n + 1}
+		for i := 0; i < len(line); {
+			switch {
+			case comment:
+				end := strings.Index(line[i:], "*/")
+				if end < 0 {
+					i = len(line)
+					continue
+				}
+				comment, i = false, i+end+2
+			case line[i] == ' ' || line[i] == '\t' || line[i] == '\r':
+				i++
+			case strings.HasPrefix(line[i:], "/*"):
+				comment, i = true, i+2
+			case line[i] == '#' || strings.HasPrefix(line[i:], "//"):
+				i = len(line)
+			case line[i] == '"':
+				var b strings.Builder
+				i++
+				for i < len(line) && line[i] != '"' {
+					if line[i] == '\\' && i+1 < len(line) {
+						i++
+					}
+					b.WriteByte(line[i])
+					i++
+				}
+				if i >= len(line) {
+					return nil, fmt.Errorf("Line %d: string is not terminated", n+1)
+				}
+				s.tokens = append(s.tokens, b.String())
+				i++
+			default:
+				end := strings.IndexAny(line[i:], " \t\r\"")
+				if end < 0 {
+					end = len(line) - i
+				}
+				s.tokens = append(s.tokens, line[i:i+end])
+				i += end
+			}
+		}
+		if len(s.tokens) > 1 && s.tokens[len(s.tokens)-1] == "{" {
+			s.tokens, s.open = s.tokens[:len(s.tokens)-1], true
+		}
+		if len(s.tokens) > 0 {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+// importStructurizr creates a definition for the workspace and each of its elements below
+// basedir and returns the created files. Properties written by the export are mapped back to
+// types, teams, tags and interfaces.
+func importStructurizr(data []byte, basedir string, globs []string, filesys billy.Filesystem) ([]string, error) {
+	created := []string{}
+	ws, err := parseStructurizr(data)
+	if err != nil {
+		return created, err
+	}
+	def, err := definitionFile(globs)
+	if err != nil {
+		return created, err
+	}
+	if _, err := filesys.Stat(filepath.Join(basedir, def)); err == nil {
+		return created, fmt.Errorf("'%s' already exists, please import into an empty base", filepath.Join(basedir, def))
+	}
+
+	// identifiers are registered both flat and hierarchical
+	byID := map[string]*structurizrElement{}
+	var register func([]*structurizrElement, string)
+	register = func(elems []*structurizrElement, path string) {
+		used := map[string]bool{}
+		for _, e := range elems {
+			e.fragment = e.id
+			if e.fragment == "" {
+				e.fragment = strings.Trim(structurizrInvalid.ReplaceAllString(e.name, "-"), "-")
+			}
+			for base, n := e.fragment, 2; used[e.fragment] || e.fragment == ""; n++ {
+				e.fragment = fmt.Sprintf("%s-%d", strings.TrimSuffix(base, "-"), n)
+			}
+			used[e.fragment] = true
+			if e.id != "" {
+				byID[e.id] = e
+				byID[path+e.id] = e
+			}
+			register(e.children, path+e.id+".")
+		}
+	}
+	register(ws.elements, "")
+
+	interfaces := map[*structurizrElement]map[string]string{}
+	dependencies := map[*structurizrElement]*yaml.Node{}
+	for _, r := range ws.relationships {
+		source, ok := r.from, r.source == ""
+		if !ok {
+			source, ok = byID[r.source]
+		}
+		destination, found := byID[r.destination]
+		if !ok || !found {
+			return created, fmt.Errorf("Line %d: relationship between unknown elements '%s' and '%s'", r.line, r.source, r.destination)
+		}
+		target := strings.Join(destination.position(), ".")
+		if i := r.properties[structurizrInterface]; i != "" {
+			if interfaces[destination] == nil {
+				interfaces[destination] = map[string]string{}
+			}
+			if _, ok := interfaces[destination][i]; !ok {
+				interfaces[destination][i] = ""
+			}
+			target += "." + i
+		}
+		if dependencies[source] == nil {
+			dependencies[source] = &yaml.Node{Kind: yaml.MappingNode}
+		}
+		fragment := destination.fragment
+		for n := 2; lookupNode(dependencies[source], []string{fragment}) != nil; n++ {
+			fragment = fmt.Sprintf("%s-%d", destination.fragment, n)
+		}
+		description, ok := r.properties[structurizrDescription]
+		if !ok {
+			description = r.description
+		}
+		dep := mappingNode("depends_on", target, "description", description, "protocol", r.technology,
+			"kind", r.properties[structurizrKind], "criticality", r.properties[structurizrCriticality])
+		if tags := prefixedProperties(r.properties, structurizrTag); len(tags.Content) > 0 {
+			dep.Content = append(dep.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "tags"}, tags)
+		}
+		dependencies[source].Content = append(dependencies[source].Content, &yaml.Node{Kind: yaml.ScalarNode, Value: fragment}, dep)
+	}
+
+	files := map[string][]byte{}
+	root := mappingNode("name", ws.name)
+	files[filepath.Join(basedir, def)], err = newDefinition(filepath.Join(basedir, def), root)
+	if err != nil {
+		return created, err
+	}
+	var add func([]*structurizrElement) error
+	add = func(elems []*structurizrElement) error {
+		for _, e := range elems {
+			file := filepath.Join(append(append([]string{basedir}, e.position()...), def)...)
+			data, err := newDefinition(file, e.definition(interfaces[e], dependencies[e]))
+			if err != nil {
+				return err
+			}
+			if e.description != "" {
+				data = append(data, []byte(fmt.Sprintf("# %s\n\n%s\n", e.name, e.description))...)
+			}
+			files[file], err = formatDefinition(file, data)
+			if err != nil {
+				return err
+			}
+			err = add(e.children)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	err = add(ws.elements)
+	if err != nil {
+		return created, err
+	}
+
+	for _, file := range sortedKeys(files) {
+		err = util.WriteFile(filesys, file, files[file], 0644)
+		if err != nil {
+			return created, fmt.Errorf("Could not write '%s': %w", file, err)
+		}
+		created = append(created, file)
+	}
+	return created, nil
+}
+
+func (e *structurizrElement) position() []string {
+	if e.parent == nil {
+		return []string{e.fragment}
+	}
+	return append(e.parent.position(), e.fragment)
+}
+
+// definition returns the front matter of the element
+func (e *structurizrElement) definition(interfaces map[string]string, dependencies *yaml.Node) *yaml.Node {
+	t := e.properties[structurizrType]
+	switch {
+	case t != "":
+	case e.kind == "container" && contains(e.tags, "Database"):
+		t = "database"
+	case e.kind == "container" && contains(e.tags, "Queue"):
+		t = "queue"
+	default:
+		t = map[string]string{"person": "person", "softwaresystem": "software_system", "container": "container", "component": "component"}[e.kind]
+	}
+	n := mappingNode("name", e.name, "type", t)
+	tags := prefixedProperties(e.properties, structurizrTag)
+	if e.technology != "" && lookupNode(tags, []string{"technology"}) == nil {
+		tags.Content = append(tags.Content, mappingNode("technology", e.technology).Content...)
+	}
+	if len(tags.Content) > 0 {
+		n.Content = append(n.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "tags"}, tags)
+	}
+	if team := e.properties[structurizrTeam]; team != "" {
+		n.Content = append(n.Content, nestedNode([]string{"owner"}, mappingNode("team", team)).Content...)
+	}
+	if dependencies != nil {
+		n.Content = append(n.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "dependencies"}, dependencies)
+	}
+	for k, v := range e.properties {
+		if i := strings.TrimPrefix(k, structurizrInterface+"."); i != k {
+			if interfaces == nil {
+				interfaces = map[string]string{}
+			}
+			interfaces[i] = v
+		}
+	}
+	if len(interfaces) > 0 {
+		intf := &yaml.Node{Kind: yaml.MappingNode}
+		for _, i := range sortedKeys(interfaces) {
+			intf.Content = append(intf.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: i}, mappingNode("description", interfaces[i]))
+		}
+		n.Content = append(n.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "interfaces"}, intf)
+	}
+	return n
+}
+
+// prefixedProperties returns the properties with the prefix as mapping without the prefix
+func prefixedProperties(props map[string]string, prefix string) *yaml.Node {
+	keys := []string{}
+	for k := range props {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	n := &yaml.Node{Kind: yaml.MappingNode}
+	for _, k := range keys {
+		n.Content = append(n.Content, mappingNode(strings.TrimPrefix(k, prefix), props[k]).Content...)
+	}
+	return n
+}
+
+// writeStructurizrExport writes the workspace to the file out
+func writeStructurizrExport(root *element, out string) error {
+	f, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	err = writeStructurizr(f, root)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("Could not write '%s': %w", out, err)
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-billy/v5/memfs"
+)
+
+func TestStructurizrRoundTrip(t *testing.T) {
+	files := map[string]string{
+		"README.md":        "---\nname: Root\n---\n",
+		"BE/README.md":     "---\nname: Backend\ntype: software_system\nowner:\n  team: backend\n---\n# Backend\n\n<!-- sysdoc:diagram -->\n<!-- /sysdoc -->\n\nServes all \"data\".\n",
+		"BE/DB/README.md":  "---\nname: Database\ntype: database\ntags:\n  technology: PostgreSQL\ninterfaces:\n  SQL:\n    description: SQL access\n---\n",
+		"FE/README.md":     "---\nname: Frontend\ntype: software_system\n---\n",
+		"FE/WEB/README.md": "---\nname: Web\ntype: container\ndependencies:\n  DB:\n    depends_on: BE.DB.SQL\n    description: reads from\n    criticality: soft\n    protocol: postgres\n  BE:\n    depends_on: BE\n---\n",
+	}
+	sys, _ := loadMemSystem(t, files)
+	var b bytes.Buffer
+	if err := writeStructurizr(&b, sys); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), `"Serves all \"data\"."`) {
+		t.Errorf("expected the first paragraph as description, got\n%s", b.String())
+	}
+
+	imported := memfs.New()
+	created, err := importStructurizr(b.Bytes(), ".", []string{"README.md"}, imported)
+	if err != nil {
+		t.Fatalf("%v\n%s", err, b.String())
+	}
+	if len(created) != len(files) {
+		t.Errorf("expected %d files, got %v", len(files), created)
+	}
+	sys = loadTestSystem(t, imported, ".", []string{"README.md"}, config{})
+	got := []string{}
+	for _, e := range sys.getElements() {
+		got = append(got, strings.Join([]string{e.getID("."), e.name, e.elementType, e.effectiveOwner().Team, formatTags(e.tags)}, "|"))
+	}
+	for _, i := range sys.getInterfaces() {
+		got = append(got, i.getID("."))
+	}
+	for _, d := range sys.getDependencies() {
+		got = append(got, d.belongsTo.getID(".")+">"+d.targetID(".")+"|"+d.description+"|"+d.criticality+"|"+d.protocol)
+	}
+	sort.Strings(got)
+	want := []string{
+		"|Root|||",
+		"BE.DB.SQL",
+		"BE.DB|Database|database|backend|technology=PostgreSQL",
+		"BE|Backend|software_system|backend|",
+		"FE.WEB>BE.DB.SQL|reads from|soft|postgres",
+		"FE.WEB>BE||hard|",
+		"FE.WEB|Web|container||",
+		"FE|Frontend|software_system||",
+	}
+	sort.Strings(want)
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("expected\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+
+	if _, err := importStructurizr(b.Bytes(), ".", []string{"README.md"}, imported); err == nil {
+		t.Errorf("expected an error when importing into an existing base")
+	}
+}
+
+func TestParseStructurizr(t *testing.T) {
+	dsl := `workspace "Shop" "An online shop" {
+    /* written by hand */
+    model {
+        customer = person "Customer"
+        shop = softwareSystem "Shop" {
+            group "Core" {
+                api = container "API" "Serves the web" "Go" {
+                    properties {
+                        "owner" "team-a"
+                    }
+                    -> db "Reads from" "JDBC"
+                }
+            }
+            db = container "Database" {
+                tags "Database"
+            }
+        }
+        customer -> shop.api "Browses" // in a browser
+    }
+    views {
+        systemContext shop {
+            include *
+        }
+    }
+}
+`
+	ws, err := parseStructurizr([]byte(dsl))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ws.name != "Shop" {
+		t.Errorf("expected workspace 'Shop', got '%s'", ws.name)
+	}
+	imported := memfs.New()
+	if _, err := importStructurizr([]byte(dsl), "sys", []string{"README.md"}, imported); err != nil {
+		t.Fatal(err)
+	}
+	sys := loadTestSystem(t, imported, "sys", []string{"README.md"}, config{})
+	got := []string{}
+	for _, e := range sys.getElements() {
+		got = append(got, e.getID(".")+"|"+e.elementType)
+	}
+	for _, d := range sys.getDependencies() {
+		got = append(got, d.belongsTo.getID(".")+">"+d.targetID("."))
+	}
+	sort.Strings(got)
+	want := []string{"|", "customer>shop.api", "customer|person", "shop.api>shop.db", "shop.api|container", "shop.db|database", "shop|software_system"}
+	sort.Strings(want)
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	for _, invalid := range []string{
+		"workspace {\n  !include other.dsl\n}\n",
+		"workspace {\n  model {\n    a = softwareSystem \"A\"\n    a -> b\n  }\n}\n",
+		"workspace {\n  model {\n",
+	} {
+		if _, err := importStructurizr([]byte(invalid), ".", []string{"README.md"}, memfs.New()); err == nil {
+			t.Errorf("expected an error for\n%s", invalid)
+		}
+	}
+}
+
+func TestStructurizrDuplicateRelationships(t *testing.T) {
+	files := map[string]string{
+		"README.md":         "---\nname: Root\n---\n",
+		"X/README.md":       "---\nname: X\ninterfaces:\n  API: {}\n---\n",
+		"A/README.md":       "---\nname: A\n---\n",
+		"A/B/README.md":     "---\nname: B\n---\n",
+		"A/B/C/README.md":   "---\nname: C\ndependencies:\n  X: {depends_on: X}\n  API: {depends_on: X.API, description: queries}\n---\n",
+		"A/B/C/D/README.md": "---\nname: D\ndependencies:\n  X: {depends_on: X}\n  API: {depends_on: X.API, description: queries}\n  Other: {depends_on: X.API}\n---\n",
+	}
+	sys, _ := loadMemSystem(t, files)
+	var b bytes.Buffer
+	if err := writeStructurizr(&b, sys); err == nil {
+		t.Errorf("expected an error for elements nested deeper than components")
+	}
+	if err := sys.apply(filter{Depth: 3}, config{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeStructurizr(&b, sys); err != nil {
+		t.Fatal(err)
+	}
+
+	relationships := []string{}
+	for _, line := range strings.Split(b.String(), "\n") {
+		if strings.Contains(line, "->") {
+			relationships = append(relationships, strings.TrimSuffix(strings.TrimSpace(line), " {"))
+		}
+	}
+	sort.Strings(relationships)
+	want := []string{
+		`A.B.C -> X "Uses (2)"`,
+		`A.B.C -> X "Uses (API)"`,
+		`A.B.C -> X "Uses"`,
+		`A.B.C -> X "queries (API)"`,
+		`A.B.C -> X "queries"`,
+	}
+	if !reflect.DeepEqual(want, relationships) {
+		t.Errorf("expected\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(relationships, "\n"))
+	}
+	imported := memfs.New()
+	if _, err := importStructurizr(b.Bytes(), ".", []string{"README.md"}, imported); err != nil {
+		t.Fatal(err)
+	}
+	sys = loadTestSystem(t, imported, ".", []string{"README.md"}, config{})
+	descriptions := []string{}
+	for _, d := range sys.getDependencies() {
+		descriptions = append(descriptions, d.description)
+	}
+	sort.Strings(descriptions)
+	if want := []string{"", "", "", "queries", "queries"}; !reflect.DeepEqual(want, descriptions) {
+		t.Errorf("expected the original descriptions %q, got %q", want, descriptions)
+	}
+}
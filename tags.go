@@ -0,0 +1,66 @@
+package main
+
+type tagsConfig struct {
+	// Inherit lists the tag keys which are passed on from elements to their children,
+	// interfaces and dependencies
+	Inherit struct {
+		Elements     []string `yaml:"elements"`
+		Interfaces   []string `yaml:"interfaces"`
+		Dependencies []string `yaml:"dependencies"`
+	} `yaml:"inherit"`
+	// Defaults are applied to all elements whose path relative to the base matches
+	// the glob, where '**' matches any number of directories
+	Defaults []tagDefault `yaml:"defaults"`
+}
+
+type tagDefault struct {
+	Glob string            `yaml:"glob"`
+	Tags map[string]string `yaml:"tags"`
+}
+
+func inheritTags(keys []string, from map[string]string, to map[string]string) {
+	for _, key := range keys {
+		if value, ok := from[key]; ok {
+			to[key] = value
+		}
+	}
+}
+
+func copyTags(from map[string]string, to map[string]string) {
+	for key, value := range from {
+		to[key] = value
+	}
+}
+
+// applyTags calculates the effective tags of the element, its interfaces, dependencies
+// and children. Inherited tags are overwritten by default tags, which are overwritten by
+// the tags declared on the element itself.
+func (e *element) applyTags(c tagsConfig, inherited map[string]string) {
+	tags := map[string]string{}
+	inheritTags(c.Inherit.Elements, inherited, tags)
+	if e.parent != nil {
+		for _, d := range c.Defaults {
+			if matchPosition(positionFromID(d.Glob, "/"), e.position()[1:]) {
+				copyTags(d.Tags, tags)
+			}
+		}
+	}
+	copyTags(e.declaredTags, tags)
+	e.tags = tags
+
+	for _, i := range e.interfaces {
+		tags := map[string]string{}
+		inheritTags(c.Inherit.Interfaces, e.tags, tags)
+		copyTags(i.declaredTags, tags)
+		i.tags = tags
+	}
+	for _, d := range e.dependencies {
+		tags := map[string]string{}
+		inheritTags(c.Inherit.Dependencies, e.tags, tags)
+		copyTags(d.declaredTags, tags)
+		d.tags = tags
+	}
+	for _, child := range e.children {
+		child.applyTags(c, e.tags)
+	}
+}
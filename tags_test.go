@@ -0,0 +1,62 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestApplyTags(t *testing.T) {
+	root := newElement("root", elementConfiguration{})
+	ext := newElement("EXT", elementConfiguration{
+		Tags: map[string]string{"external": "true", "link": "https://example.com"},
+	})
+	svc := newElement("SVC", elementConfiguration{
+		Tags:         map[string]string{"obsolete": "true"},
+		Interfaces:   map[string]interfConfiguration{"API": {}},
+		Dependencies: map[string]dependencyConfiguration{"ToDB": {Tags: map[string]string{"manual": "true"}}},
+	})
+	other := newElement("OTHER", elementConfiguration{
+		Tags: map[string]string{"team": "declared"},
+	})
+	for _, tc := range []struct {
+		e   *element
+		pos []string
+	}{
+		{e: ext, pos: []string{"EXT"}},
+		{e: svc, pos: []string{"EXT", "SVC"}},
+		{e: other, pos: []string{"OTHER"}},
+	} {
+		err := root.appendAt(tc.e, tc.pos)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c := tagsConfig{}
+	c.Inherit.Elements = []string{"external", "team"}
+	c.Inherit.Interfaces = []string{"external", "obsolete"}
+	c.Inherit.Dependencies = []string{"external"}
+	c.Defaults = []tagDefault{
+		{Glob: "**", Tags: map[string]string{"team": "default"}},
+		{Glob: "EXT/*", Tags: map[string]string{"external": "false"}},
+	}
+	root.applyTags(c, map[string]string{})
+
+	tests := []struct {
+		name string
+		got  map[string]string
+		want map[string]string
+	}{
+		{name: "EXT", got: ext.tags, want: map[string]string{"external": "true", "link": "https://example.com", "team": "default"}},
+		{name: "EXT.SVC", got: svc.tags, want: map[string]string{"external": "false", "obsolete": "true", "team": "default"}},
+		{name: "OTHER", got: other.tags, want: map[string]string{"team": "declared"}},
+		{name: "EXT.SVC.API", got: svc.interfaces[0].tags, want: map[string]string{"external": "false", "obsolete": "true"}},
+		{name: "EXT.SVC.ToDB", got: svc.dependencies[0].tags, want: map[string]string{"external": "false", "manual": "true"}},
+		{name: "declared EXT.SVC", got: svc.declaredTags, want: map[string]string{"obsolete": "true"}},
+	}
+	for _, tc := range tests {
+		if !reflect.DeepEqual(tc.want, tc.got) {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.want, tc.got)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	viewContext   = "context"
+	viewContainer = "container"
+	viewComponent = "component"
+)
+
+var views = []string{viewContext, viewContainer, viewComponent}
+
+// elementTypes maps the built in element types to the C4 view they first appear in
+var elementTypes = map[string]string{
+	"person":          viewContext,
+	"software_system": viewContext,
+	"external_system": viewContext,
+	"container":       viewContainer,
+	"database":        viewContainer,
+	"queue":           viewContainer,
+	"component":       viewComponent,
+}
+
+type typesConfig struct {
+	// Additional defines custom element types along with the C4 view they first appear in
+	Additional map[string]string `yaml:"additional"`
+	// Children lists the types of children allowed per element type, types not
+	// listed allow children of any type
+	Children map[string][]string `yaml:"children"`
+}
+
+func (c typesConfig) validate() error {
+	for t, view := range c.Additional {
+		if !contains(views, view) {
+			return fmt.Errorf("View '%s' of type '%s' is not valid, please choose one of %v", view, t, views)
+		}
+	}
+	return nil
+}
+
+func (c typesConfig) viewOf(t string) (string, bool) {
+	if view, ok := elementTypes[t]; ok {
+		return view, true
+	}
+	view, ok := c.Additional[t]
+	return view, ok
+}
+
+func (c typesConfig) known() []string {
+	out := []string{}
+	for t := range elementTypes {
+		out = append(out, t)
+	}
+	for t := range c.Additional {
+		out = append(out, t)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// check returns an error for every element below root with an unknown type or with
+// a type not allowed below the type of its closest typed parent
+func (c typesConfig) check(root *element) []error {
+	errs := []error{}
+	for _, e := range root.getElements() {
+		if e.elementType == "" {
+			continue
+		}
+		id := strings.Join(e.position(), ".")
+		if _, ok := c.viewOf(e.elementType); !ok {
+			errs = append(errs, fmt.Errorf("Type '%s' of element '%s' is not valid, please choose one of %v", e.elementType, id, c.known()))
+			continue
+		}
+		parent := e.parent
+		for parent != nil && parent.elementType == "" {
+			parent = parent.parent
+		}
+		if parent == nil {
+			continue
+		}
+		allowed, ok := c.Children[parent.elementType]
+		if ok && !contains(allowed, e.elementType) {
+			errs = append(errs, fmt.Errorf("Element '%s' of type '%s' is not allowed below type '%s', please choose one of %v", id, e.elementType, parent.elementType, allowed))
+		}
+	}
+	return errs
+}
+
+// view collapses all elements which do not belong to the given C4 view into their closest
+// visible parent. Interfaces and dependencies of collapsed elements are moved to this
+// parent, dependencies within the same element are removed. It needs to be called before
+// the interfaces are propagated.
+func (root *element) view(view string, c typesConfig) error {
+	level := indexOf(views, view)
+	if level < 0 {
+		return fmt.Errorf("View '%s' is not valid, please choose one of %v", view, views)
+	}
+	root.collapse(level, c)
+	root.tidyCollapsed()
+	return nil
+}
+
+// limitDepth collapses all elements nested deeper than depth into their ancestor at depth,
+// the root element has depth 0. It needs to be called before the interfaces are propagated.
+func (root *element) limitDepth(depth int) {
+	for _, e := range root.getElements() {
+		if len(e.position())-1 == depth {
+			for _, child := range e.children {
+				e.absorb(child)
+			}
+			e.children = []*element{}
+		}
+	}
+	root.tidyCollapsed()
+}
+
+// tidyCollapsed points dependencies on collapsed elements to their closest visible parent
+// and removes dependencies within the same element
+func (root *element) tidyCollapsed() {
+	visible := map[*element]bool{}
+	for _, e := range root.getElements() {
+		visible[e] = true
+	}
+	for _, e := range root.getElements() {
+		dependencies := []*dependency{}
+		for _, d := range e.dependencies {
+			for d.dependsOnElement != nil && !visible[d.dependsOnElement] {
+				d.dependsOnElement = d.dependsOnElement.parent
+			}
+			if d.provider() != e {
+				dependencies = append(dependencies, d)
+			}
+		}
+		e.dependencies = dependencies
+	}
+}
+
+func (e *element) collapse(level int, c typesConfig) {
+	children := []*element{}
+	for _, child := range e.children {
+		if view, ok := c.viewOf(child.elementType); ok && indexOf(views, view) > level {
+			e.absorb(child)
+			continue
+		}
+		child.collapse(level, c)
+		children = append(children, child)
+	}
+	e.children = children
+}
+
+// absorb moves all interfaces and dependencies of the element h and its children to the
+// element, their fragments are suffixed with the position of their origin
+func (e *element) absorb(h *element) {
+	for _, x := range h.getElements() {
+		suffix := strings.Join(x.position()[len(e.position()):], "-")
+		for _, i := range x.interfaces {
+			i.fragment = fmt.Sprintf("%s-%s", i.fragment, suffix)
+			i.belongsTo = e
+			e.interfaces = append(e.interfaces, i)
+		}
+		for _, d := range x.dependencies {
+			d.fragment = fmt.Sprintf("%s-%s", d.fragment, suffix)
+			d.belongsTo = e
+			e.dependencies = append(e.dependencies, d)
+		}
+	}
+}
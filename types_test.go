@@ -0,0 +1,86 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"sysdoc/internal/persistence"
+	"testing"
+)
+
+func TestTypesCheck(t *testing.T) {
+	root := newElement("root", elementConfiguration{})
+	sys := newElement("SYS", elementConfiguration{Type: "software_system"})
+	group := newElement("GROUP", elementConfiguration{})
+	comp := newElement("COMP", elementConfiguration{Type: "component"})
+	job := newElement("JOB", elementConfiguration{Type: "cronjob"})
+	user := newElement("USER", elementConfiguration{Tags: map[string]string{"type": "user"}})
+	for _, tc := range []struct {
+		e   *element
+		pos []string
+	}{
+		{e: sys, pos: []string{"SYS"}},
+		{e: group, pos: []string{"SYS", "GROUP"}},
+		{e: comp, pos: []string{"SYS", "GROUP", "COMP"}},
+		{e: job, pos: []string{"SYS", "JOB"}},
+		{e: user, pos: []string{"USER"}},
+	} {
+		err := root.appendAt(tc.e, tc.pos)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if user.elementType != "person" {
+		t.Errorf("expected elements tagged as user to be of type person, got '%s'", user.elementType)
+	}
+
+	c := typesConfig{
+		Children: map[string][]string{"software_system": {"container", "database"}},
+	}
+	errs := c.check(root)
+	want := []string{
+		"Element 'root.SYS.GROUP.COMP' of type 'component' is not allowed below type 'software_system'",
+		"Type 'cronjob' of element 'root.SYS.JOB' is not valid",
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("expected %d errors, got %v", len(want), errs)
+	}
+	for i := range want {
+		if !strings.HasPrefix(errs[i].Error(), want[i]) {
+			t.Errorf("expected '%s', got '%s'", want[i], errs[i])
+		}
+	}
+
+	c.Additional = map[string]string{"cronjob": viewContainer}
+	c.Children["software_system"] = append(c.Children["software_system"], "cronjob", "component")
+	if errs := c.check(root); len(errs) > 0 {
+		t.Errorf("expected no errors, got %v", errs)
+	}
+}
+
+func TestView(t *testing.T) {
+	p, err := persistence.NewLocal("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sys, errs := NewSystem(".", []string{"README.md"}, filter{View: viewContext}, config{}, p)
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+
+	ids := []string{}
+	for _, e := range sys.getElements() {
+		ids = append(ids, e.getID("."))
+	}
+	if want := []string{"", "BE", "FE"}; !reflect.DeepEqual(want, ids) {
+		t.Errorf("expected elements %v, got %v", want, ids)
+	}
+
+	deps := []string{}
+	for _, d := range sys.getDependencies() {
+		deps = append(deps, d.belongsTo.getID(".")+" -> "+d.dependsOn.getID("."))
+	}
+	if want := []string{"FE -> BE.API-SVC"}; !reflect.DeepEqual(want, deps) {
+		t.Errorf("expected dependencies %v, got %v", want, deps)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/go-git/go-billy/v5"
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+)
+
+// watch calls fn once and again whenever a file below basedir changes until done is closed.
+// Changes are detected by polling the modification times and sizes in the given interval.
+// Hidden files, files ignored by .sysdocignore files and the files listed in skip are not
+// watched. Errors while polling are passed to fail and polling continues.
+func watch(filesys billy.Filesystem, basedir string, interval time.Duration, skip []string, done <-chan struct{}, fn func(), fail func(error)) {
+	last, err := snapshot(filesys, basedir, skip)
+	if err != nil {
+		fail(err)
+	}
+	fn()
+	for {
+		select {
+		case <-done:
+			return
+		case <-time.After(interval):
+		}
+		current, err := snapshot(filesys, basedir, skip)
+		if err != nil {
+			fail(err)
+			continue
+		}
+		if equalSnapshots(last, current) {
+			continue
+		}
+		last = current
+		fn()
+	}
+}
+
+// snapshot returns the modification time and size of every watched file below basedir
+func snapshot(filesys billy.Filesystem, basedir string, skip []string) (map[string]string, error) {
+	basedir = filepath.Clean(basedir)
+	out := map[string]string{}
+	skipped := map[string]bool{}
+	for _, s := range skip {
+		skipped[filepath.Clean(s)] = true
+	}
+	var walk func(string, []gitignore.Pattern) error
+	walk = func(dir string, ignore []gitignore.Pattern) error {
+		patterns, err := readIgnoreFile(dir, getPosition(basedir, dir), filesys)
+		if err != nil {
+			return err
+		}
+		ignore = append(ignore, patterns...)
+		matcher := gitignore.NewMatcher(ignore)
+		elems, err := filesys.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		for _, elem := range elems {
+			path := filepath.Join(dir, elem.Name())
+			if skipped[path] || (isHidden(elem) && elem.Name() != ignoreFile) {
+				continue
+			}
+			if matcher.Match(getPosition(basedir, path), elem.IsDir()) {
+				continue
+			}
+			if elem.IsDir() {
+				err = walk(path, ignore)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+			out[path] = fmt.Sprintf("%d/%d", elem.ModTime().UnixNano(), elem.Size())
+		}
+		return nil
+	}
+	err := walk(basedir, []gitignore.Pattern{})
+	if err != nil {
+		return nil, fmt.Errorf("Could not watch '%s': %w", basedir, err)
+	}
+	return out, nil
+}
+
+func equalSnapshots(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for path, state := range a {
+		if b[path] != state {
+			return false
+		}
+	}
+	return true
+}
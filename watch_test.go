@@ -0,0 +1,126 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v5/osfs"
+	"github.com/go-git/go-billy/v5/util"
+)
+
+func TestSnapshot(t *testing.T) {
+	fs := osfs.New(t.TempDir())
+	files := map[string]string{
+		"README.md":                "---\nname: Root\n---\n",
+		".sysdocignore":            "build\n",
+		"BE/README.md":             "---\nname: Backend\n---\n",
+		"build/out.svg":            "<svg/>",
+		".git/HEAD":                "ref: refs/heads/master\n",
+		"diagram.svg":              "<svg/>",
+		"node_modules/x/README.md": "not ignored\n",
+		"BE/.sysdocignore":         "*.tmp\n",
+		"BE/scratch.tmp":           "tmp",
+	}
+	writeTestFiles(t, fs, files)
+	before, err := snapshot(fs, ".", []string{"diagram.svg"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{".sysdocignore", "BE/.sysdocignore", "BE/README.md", "README.md", "node_modules/x/README.md"}
+	got := sortedKeys(before)
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("expected %v, got %v", want, got)
+		}
+	}
+
+	for _, name := range []string{"diagram.svg", "build/out.svg", "BE/scratch.tmp"} {
+		err = util.WriteFile(fs, name, []byte("changed content"), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	after, err := snapshot(fs, ".", []string{"diagram.svg"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equalSnapshots(before, after) {
+		t.Errorf("expected changes of unwatched files to be ignored")
+	}
+
+	err = util.WriteFile(fs, "BE/README.md", []byte("---\nname: Changed backend\n---\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	after, err = snapshot(fs, ".", []string{"diagram.svg"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if equalSnapshots(before, after) {
+		t.Errorf("expected the change of a definition to be detected")
+	}
+}
+
+func TestWatch(t *testing.T) {
+	fs := osfs.New(t.TempDir())
+	err := util.WriteFile(fs, "README.md", []byte("---\nname: Root\n---\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	calls := 0
+	watch(fs, ".", time.Millisecond, []string{}, done, func() {
+		calls++
+		switch calls {
+		case 1:
+			err := util.WriteFile(fs, "BE/README.md", []byte("---\nname: Backend\n---\n"), 0644)
+			if err != nil {
+				t.Error(err)
+			}
+		default:
+			close(done)
+		}
+	}, func(err error) {
+		t.Error(err)
+	})
+	if calls != 2 {
+		t.Errorf("expected 2 calls, got %d", calls)
+	}
+}
+
+func TestWatchErrors(t *testing.T) {
+	fs := osfs.New(t.TempDir())
+	err := util.WriteFile(fs, "sys/README.md", []byte("---\nname: Root\n---\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	calls, failures := 0, 0
+	watch(fs, "sys", time.Millisecond, []string{}, done, func() {
+		calls++
+		switch calls {
+		case 1:
+			// the base vanishes while watching
+			err := util.RemoveAll(fs, "sys")
+			if err != nil {
+				t.Error(err)
+			}
+		default:
+			close(done)
+		}
+	}, func(err error) {
+		failures++
+		if failures == 1 {
+			err := util.WriteFile(fs, "sys/README.md", []byte("---\nname: Restored\n---\n"), 0644)
+			if err != nil {
+				t.Error(err)
+			}
+		}
+	})
+	if calls != 2 || failures == 0 {
+		t.Errorf("expected to keep watching after errors, got %d calls and %d failures", calls, failures)
+	}
+}